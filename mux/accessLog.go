@@ -0,0 +1,150 @@
+package mux
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// AccessLogFormat is the format of the access log line the mux writes to
+// its logger for every request.
+type AccessLogFormat int
+
+const (
+	// AccessLogNone disables access logs, this is the default
+	AccessLogNone AccessLogFormat = iota
+	// AccessLogCommon writes lines in the Common Log Format
+	AccessLogCommon
+	// AccessLogCombined writes lines in the Combined Log Format, which adds
+	// the referer and user agent to the Common Log Format
+	AccessLogCombined
+	// AccessLogJSON writes one JSON object per line, this is the only format
+	// that includes the route template, duration and request ID
+	AccessLogJSON
+)
+
+// clfTimeFormat is the time format used by the Common Log Format
+const clfTimeFormat = "02/Jan/2006:15:04:05 -0700"
+
+// accessEntry holds the information that is logged for a request
+type accessEntry struct {
+	Time       time.Time `json:"time"`
+	RemoteAddr string    `json:"remote_addr"`
+	User       string    `json:"user,omitempty"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Route      string    `json:"route,omitempty"`
+	Proto      string    `json:"proto"`
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"`
+	Duration   float64   `json:"duration_ms"`
+	Referer    string    `json:"referer,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	RequestID  string    `json:"request_id,omitempty"`
+}
+
+// AccessLog sets the format of the access log line written to the logger
+// for every request, access logs are written at the info level. Structured
+// loggers receive the fields of the request instead of a formatted line.
+func (m *Mux) AccessLog(format AccessLogFormat) {
+	m.accessLog = format
+}
+
+// logAccess writes the access log line for the request
+func (m *Mux) logAccess(r *http.Request, state *requestState, w *statusWriter, start time.Time) {
+	if (m.logger == nil && m.structured == nil) || m.accessLog == AccessLogNone || LevelInfo < m.logLevel {
+		return
+	}
+
+	entry := accessEntry{
+		Time:       start,
+		RemoteAddr: remoteHost(r),
+		Method:     r.Method,
+		Path:       r.URL.RequestURI(),
+		Proto:      r.Proto,
+		Status:     w.Status(),
+		Bytes:      w.bytes,
+		Duration:   float64(time.Since(start).Microseconds()) / 1000,
+		Referer:    r.Referer(),
+		UserAgent:  r.UserAgent(),
+		RequestID:  state.requestID,
+	}
+
+	if user, _, ok := r.BasicAuth(); ok {
+		entry.User = user
+	}
+
+	if state.route != nil {
+		entry.Route = state.route.template()
+	}
+
+	if m.structured != nil {
+		m.structured.Log(LevelInfo, "Request served", entry.fields()...)
+		return
+	}
+
+	m.logf(LevelInfo, "%s", entry.format(m.accessLog))
+}
+
+// fields returns the entry as key/value pairs for structured loggers
+func (e accessEntry) fields() []interface{} {
+	return []interface{}{
+		"method", e.Method,
+		"path", e.Path,
+		"route", e.Route,
+		"status", e.Status,
+		"bytes", e.Bytes,
+		"duration_ms", e.Duration,
+		"remote_addr", e.RemoteAddr,
+		"request_id", e.RequestID,
+	}
+}
+
+// format returns the log line for the entry in the format provided
+func (e accessEntry) format(format AccessLogFormat) string {
+	if format == AccessLogJSON {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err.Error()
+		}
+
+		return string(line)
+	}
+
+	bytes := "-"
+	if e.Bytes > 0 {
+		bytes = strconv.FormatInt(e.Bytes, 10)
+	}
+
+	line := fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s",
+		e.RemoteAddr, orDash(e.User), e.Time.Format(clfTimeFormat),
+		e.Method, e.Path, e.Proto, e.Status, bytes)
+
+	if format == AccessLogCombined {
+		line += fmt.Sprintf(" %q %q", orDash(e.Referer), orDash(e.UserAgent))
+	}
+
+	return line
+}
+
+// remoteHost returns the host of the client without the port
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// orDash returns "-" for empty values as the log formats expect
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
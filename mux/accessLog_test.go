@@ -0,0 +1,115 @@
+package mux
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+// recordingLogger stores every log line by level
+type recordingLogger struct {
+	lines map[string][]string
+}
+
+func newRecordingLogger() *recordingLogger {
+	return &recordingLogger{lines: make(map[string][]string)}
+}
+
+func (l *recordingLogger) record(level, format string, data ...interface{}) {
+	l.lines[level] = append(l.lines[level], fmt.Sprintf(format, data...))
+}
+
+func (l *recordingLogger) Info(format string, data ...interface{}) { l.record("info", format, data...) }
+func (l *recordingLogger) Warn(format string, data ...interface{}) { l.record("warn", format, data...) }
+func (l *recordingLogger) Debug(format string, data ...interface{}) {
+	l.record("debug", format, data...)
+}
+func (l *recordingLogger) Error(format string, data ...interface{}) {
+	l.record("error", format, data...)
+}
+
+var accessLogTests = []struct {
+	description, requestURL string
+	format                  AccessLogFormat
+	expectedPattern         string
+}{{
+	description:     "Testing: The common log format logs the client, request, status and bytes.",
+	requestURL:      "/users/darwin?page=2",
+	format:          AccessLogCommon,
+	expectedPattern: `^192\.0\.2\.1 - - \[[^\]]+\] "GET /users/darwin\?page=2 HTTP/1\.1" 200 6$`,
+}, {
+	description:     "Testing: The combined log format adds the referer and user agent.",
+	requestURL:      "/missing",
+	format:          AccessLogCombined,
+	expectedPattern: `^192\.0\.2\.1 - - \[[^\]]+\] "GET /missing HTTP/1\.1" 404 10 "https://example\.com/" "gowt-test"$`,
+}, {
+	description:     "Testing: The JSON format includes the route template and request ID.",
+	requestURL:      "/users/darwin",
+	format:          AccessLogJSON,
+	expectedPattern: `"route":"/users/\{name\}".*"status":200,"bytes":6.*"request_id":"abc-123"`,
+}}
+
+func TestAccessLog(t *testing.T) {
+	t.Log("Testing access log formats.")
+
+	for i, test := range accessLogTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		logger := newRecordingLogger()
+		m := NewMux()
+		m.RegisterLogger(logger)
+		m.AccessLog(test.format)
+		m.Use(RequestID(RequestIDConfig{}))
+		m.RegisterRoute("/users/{name}", writeBody("users"))
+
+		r := httptest.NewRequest("GET", test.requestURL, nil)
+		r.Header.Set("Referer", "https://example.com/")
+		r.Header.Set("User-Agent", "gowt-test")
+		r.Header.Set("X-Request-ID", "abc-123")
+
+		m.ServeHTTP(httptest.NewRecorder(), r)
+
+		lines := logger.lines["info"]
+		if len(lines) != 2 {
+			t.Logf("[FAIL] :: Expected the registration and access log lines but got %d lines.", len(lines))
+			t.FailNow()
+		}
+
+		if !regexp.MustCompile(test.expectedPattern).MatchString(lines[1]) {
+			t.Logf("[FAIL] :: Expected the line to match \"%s\" but got \"%s\".", test.expectedPattern, lines[1])
+			t.Fail()
+		}
+
+		if test.format == AccessLogJSON && !json.Valid([]byte(lines[1])) {
+			t.Logf("[FAIL] :: Expected the line to be valid JSON but got \"%s\".", lines[1])
+			t.Fail()
+		}
+	}
+}
+
+func TestRouteOverwriteWarning(t *testing.T) {
+	t.Log("Testing a warning is logged when a route is replaced.")
+
+	logger := newRecordingLogger()
+	m := NewMux()
+	m.RegisterLogger(logger)
+	m.LogLevel(LevelDebug)
+
+	m.RegisterRoute("/replaced", writeBody("first"))
+	m.RegisterRoute("/replaced", writeBody("second"))
+
+	if len(logger.lines["warn"]) != 1 {
+		t.Logf("[FAIL] :: Expected 1 warning but got %d warnings.", len(logger.lines["warn"]))
+		t.Fail()
+	}
+
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/replaced", nil))
+
+	if len(logger.lines["debug"]) != 1 {
+		t.Logf("[FAIL] :: Expected 1 debug line for the matched route but got %d lines.", len(logger.lines["debug"]))
+		t.Fail()
+	}
+}
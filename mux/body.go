@@ -0,0 +1,75 @@
+package mux
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// MaxBody limits the size of request bodies for the route to n bytes.
+// Requests with a larger Content-Length are answered with the 413 error
+// handler before the handler runs, other bodies are wrapped with
+// http.MaxBytesReader so reading past the limit returns an
+// *http.MaxBytesError, which handlers can answer with mux.Error.
+func (r *Route) MaxBody(n int64) *Route {
+	r.maxBody = n
+
+	return r
+}
+
+// Consumes restricts the media types of request bodies for the route,
+// requests with a body of another type are answered with the 415 error
+// handler. A type such as "text/*" matches every subtype.
+func (r *Route) Consumes(mediaTypes ...string) *Route {
+	for _, mediaType := range mediaTypes {
+		r.consumes = appendUnique(r.consumes, strings.ToLower(mediaType))
+	}
+
+	return r
+}
+
+// checkBody enforces the body restrictions of the route, it returns the
+// request with the body limited and false if an error was served.
+func (m *Mux) checkBody(route *Route, w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	if route.maxBody > 0 {
+		if r.ContentLength > route.maxBody {
+			m.logRequest(r, LevelDebug, "Request body is too large", "route", route.template(), "length", r.ContentLength, "limit", route.maxBody)
+			m.serveError(w, r, http.StatusRequestEntityTooLarge)
+			return r, false
+		}
+
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, route.maxBody)
+		}
+	}
+
+	if len(route.consumes) > 0 && hasBody(r) && !consumesType(route.consumes, r.Header.Get("Content-Type")) {
+		m.logRequest(r, LevelDebug, "Request body has an unsupported media type", "route", route.template(), "content_type", r.Header.Get("Content-Type"))
+		m.serveError(w, r, http.StatusUnsupportedMediaType)
+		return r, false
+	}
+
+	return r, true
+}
+
+// hasBody reports if the request has a body
+func hasBody(r *http.Request) bool {
+	return r.ContentLength > 0 || (r.ContentLength == -1 && r.Body != nil && r.Body != http.NoBody) || len(r.TransferEncoding) > 0
+}
+
+// consumesType reports if the content type is one of the media types
+func consumesType(mediaTypes []string, contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, allowed := range mediaTypes {
+		if allowed == mediaType || allowed == "*/*" ||
+			(strings.HasSuffix(allowed, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(allowed, "*"))) {
+			return true
+		}
+	}
+
+	return false
+}
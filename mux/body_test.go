@@ -0,0 +1,122 @@
+package mux
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var bodyTests = []struct {
+	description, method, requestURL, contentType, body string
+	chunked                                            bool
+	expectedStatus                                     int
+	expectedBody                                       string
+}{{
+	description:    "Testing: A body under the limit with an allowed type is served.",
+	method:         "POST",
+	requestURL:     "/json",
+	contentType:    "application/json; charset=utf-8",
+	body:           `{"a":1}`,
+	expectedStatus: http.StatusOK,
+	expectedBody:   `{"a":1}`,
+}, {
+	description:    "Testing: A Content-Length over the limit gets the 413 error handler.",
+	method:         "POST",
+	requestURL:     "/json",
+	contentType:    "application/json",
+	body:           strings.Repeat("a", 11),
+	expectedStatus: http.StatusRequestEntityTooLarge,
+	expectedBody:   "too large",
+}, {
+	description:    "Testing: A chunked body over the limit fails when it is read.",
+	method:         "POST",
+	requestURL:     "/json",
+	contentType:    "application/json",
+	body:           strings.Repeat("a", 11),
+	chunked:        true,
+	expectedStatus: http.StatusRequestEntityTooLarge,
+	expectedBody:   "too large",
+}, {
+	description:    "Testing: A body with another media type gets the 415 error handler.",
+	method:         "POST",
+	requestURL:     "/json",
+	contentType:    "text/plain",
+	body:           "a",
+	expectedStatus: http.StatusUnsupportedMediaType,
+	expectedBody:   "unsupported",
+}, {
+	description:    "Testing: A body without a content type gets the 415 error handler.",
+	method:         "POST",
+	requestURL:     "/json",
+	body:           "a",
+	expectedStatus: http.StatusUnsupportedMediaType,
+	expectedBody:   "unsupported",
+}, {
+	description:    "Testing: A request without a body isn't checked for its type.",
+	method:         "GET",
+	requestURL:     "/json",
+	expectedStatus: http.StatusOK,
+}, {
+	description:    "Testing: Wildcard media types match every subtype.",
+	method:         "POST",
+	requestURL:     "/text",
+	contentType:    "text/csv",
+	body:           "a,b",
+	expectedStatus: http.StatusOK,
+	expectedBody:   "a,b",
+}}
+
+func TestBodyRestrictions(t *testing.T) {
+	t.Log("Testing request body size limits and media types.")
+
+	for i, test := range bodyTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		m := NewMux()
+		m.RegisterErrorHandler(http.StatusRequestEntityTooLarge, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			w.Write([]byte("too large"))
+		})
+		m.RegisterErrorHandler(http.StatusUnsupportedMediaType, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			w.Write([]byte("unsupported"))
+		})
+
+		echo := func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				Error(w, r, http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			w.Write(body)
+		}
+		must(m.RegisterRoute("/json", echo)).MaxBody(10).Consumes("application/json")
+		must(m.RegisterRoute("/text", echo)).Consumes("text/*")
+
+		var body io.Reader
+		if test.body != "" {
+			body = strings.NewReader(test.body)
+		}
+		r := httptest.NewRequest(test.method, test.requestURL, body)
+		if test.chunked {
+			r.ContentLength = -1
+		}
+		if test.contentType != "" {
+			r.Header.Set("Content-Type", test.contentType)
+		}
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		if w.Code != test.expectedStatus || w.Body.String() != test.expectedBody {
+			t.Logf("[FAIL] :: Expected %d \"%s\" but got %d \"%s\".", test.expectedStatus, test.expectedBody, w.Code, w.Body.String())
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,382 @@
+package mux
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ContentEncoder compresses response bodies with a content coding, it can
+// be implemented to add codings such as brotli.
+type ContentEncoder interface {
+	// Encoding is the coding's name in Accept-Encoding and
+	// Content-Encoding, e.g. "gzip".
+	Encoding() string
+	// NewWriter returns a writer that compresses into w, it is closed when
+	// the response is done and flushed if it has a Flush() error method.
+	NewWriter(w io.Writer) io.WriteCloser
+}
+
+// GzipEncoder is the gzip ContentEncoder, the zero value uses the default
+// compression level.
+type GzipEncoder struct {
+	Level int
+}
+
+// Encoding returns "gzip"
+func (e GzipEncoder) Encoding() string {
+	return "gzip"
+}
+
+// NewWriter returns a gzip writer, invalid levels use the default level
+func (e GzipEncoder) NewWriter(w io.Writer) io.WriteCloser {
+	level := e.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	gw, err := gzip.NewWriterLevel(w, level)
+	if err != nil {
+		gw = gzip.NewWriter(w)
+	}
+
+	return gw
+}
+
+// DeflateEncoder is the deflate ContentEncoder, the zero value uses the
+// default compression level.
+type DeflateEncoder struct {
+	Level int
+}
+
+// Encoding returns "deflate"
+func (e DeflateEncoder) Encoding() string {
+	return "deflate"
+}
+
+// NewWriter returns a deflate writer, invalid levels use the default level
+func (e DeflateEncoder) NewWriter(w io.Writer) io.WriteCloser {
+	level := e.Level
+	if level == 0 {
+		level = flate.DefaultCompression
+	}
+
+	fw, err := flate.NewWriter(w, level)
+	if err != nil {
+		fw, _ = flate.NewWriter(w, flate.DefaultCompression)
+	}
+
+	return fw
+}
+
+// DefaultCompressionSkipTypes are the content types that are already
+// compressed, a type ending in "/" matches every subtype.
+var DefaultCompressionSkipTypes = []string{
+	"image/", "video/", "audio/",
+	"application/zip", "application/gzip", "application/x-gzip", "application/x-bzip2",
+	"application/x-7z-compressed", "application/x-rar-compressed", "application/zstd",
+	"font/woff", "font/woff2",
+}
+
+// CompressionConfig configures the Compress middleware
+type CompressionConfig struct {
+	// Encoders are the supported codings in order of preference, they
+	// default to gzip and deflate.
+	Encoders []ContentEncoder
+	// MinSize is the smallest body in bytes that is compressed, it
+	// defaults to 1024.
+	MinSize int
+	// SkipTypes are the content types that aren't compressed, they
+	// default to DefaultCompressionSkipTypes.
+	SkipTypes []string
+}
+
+// Compress returns middleware that compresses response bodies with the
+// coding the client prefers. Bodies smaller than the minimum size, bodies
+// of skipped content types and responses that already have a
+// Content-Encoding are sent as they are. The body is buffered until the
+// minimum size is reached or the handler flushes, flushing a compressed
+// response flushes the encoder.
+//
+// Use it on the mux to compress every route, or on a route or group to opt
+// in. Routes opt out with Route.DisableCompression.
+func Compress(config CompressionConfig) Middleware {
+	if config.Encoders == nil {
+		config.Encoders = []ContentEncoder{GzipEncoder{}, DeflateEncoder{}}
+	}
+
+	if config.MinSize == 0 {
+		config.MinSize = 1024
+	}
+
+	if config.SkipTypes == nil {
+		config.SkipTypes = DefaultCompressionSkipTypes
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cw := &compressWriter{
+				ResponseWriter: w,
+				request:        r,
+				config:         &config,
+				encoder:        negotiateEncoding(r.Header.Get("Accept-Encoding"), config.Encoders),
+			}
+			defer cw.close()
+
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// DisableCompression opts the route out of the Compress middleware
+func (r *Route) DisableCompression() *Route {
+	r.noCompression = true
+
+	return r
+}
+
+// compressWriter holds back the status and the start of the body until it
+// knows if the response should be compressed.
+type compressWriter struct {
+	http.ResponseWriter
+
+	request *http.Request
+	config  *CompressionConfig
+	encoder ContentEncoder
+
+	status  int
+	buf     []byte
+	decided bool
+	writer  io.WriteCloser
+}
+
+// WriteHeader records the status code until the encoding is decided,
+// informational status codes are passed on.
+func (cw *compressWriter) WriteHeader(code int) {
+	if code >= 100 && code <= 199 && code != http.StatusSwitchingProtocols {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	if cw.status != 0 {
+		return
+	}
+
+	cw.status = code
+
+	if !bodyAllowed(code) {
+		cw.decide(false)
+	}
+}
+
+// Write buffers the body until it reaches the minimum size, then writes
+// it through the encoder if the response is compressed.
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	if !cw.decided {
+		cw.buf = append(cw.buf, b...)
+
+		if len(cw.buf) < cw.config.MinSize {
+			return len(b), nil
+		}
+
+		if err := cw.decide(false); err != nil {
+			return 0, err
+		}
+
+		return len(b), nil
+	}
+
+	if cw.writer != nil {
+		return cw.writer.Write(b)
+	}
+
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush sends the response so far, a response that is still buffered is
+// compressed if it is eligible regardless of its size.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+
+		cw.decide(true)
+	}
+
+	if f, ok := cw.writer.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the wrapped response writer for http.ResponseController
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// close sends a response that is still buffered and closes the encoder
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if cw.status == 0 && len(cw.buf) == 0 {
+			return
+		}
+
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+
+		cw.decide(false)
+	}
+
+	if cw.writer != nil {
+		cw.writer.Close()
+	}
+}
+
+// decide writes the headers and the buffered body, compressing the
+// response if the encoding, content type and size allow it.
+func (cw *compressWriter) decide(flushing bool) error {
+	cw.decided = true
+
+	header := cw.Header()
+	if len(cw.buf) > 0 && header.Get("Content-Type") == "" && header.Get("Content-Encoding") == "" {
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+
+	if cw.compressible() {
+		addVary(header, "Accept-Encoding")
+
+		if cw.encoder != nil && (flushing || len(cw.buf) >= cw.config.MinSize) {
+			header.Set("Content-Encoding", cw.encoder.Encoding())
+			header.Del("Content-Length")
+			cw.writer = cw.encoder.NewWriter(cw.ResponseWriter)
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	if len(cw.buf) == 0 {
+		return nil
+	}
+
+	var err error
+	if cw.writer != nil {
+		_, err = cw.writer.Write(cw.buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(cw.buf)
+	}
+	cw.buf = nil
+
+	return err
+}
+
+// compressible reports if the response could be compressed, the size of
+// the body isn't considered.
+func (cw *compressWriter) compressible() bool {
+	if route := routeFromRequest(cw.request); route != nil && route.noCompression {
+		return false
+	}
+
+	header := cw.Header()
+	if !bodyAllowed(cw.status) || cw.status == http.StatusPartialContent || cw.request.Method == http.MethodHead ||
+		header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+
+	if length, err := strconv.Atoi(header.Get("Content-Length")); err == nil && length < cw.config.MinSize {
+		return false
+	}
+
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+	contentType = strings.TrimSpace(contentType)
+
+	for _, skip := range cw.config.SkipTypes {
+		if contentType == skip || (strings.HasSuffix(skip, "/") && strings.HasPrefix(contentType, skip)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// bodyAllowed reports if a response with the status can have a body
+func bodyAllowed(status int) bool {
+	return status != http.StatusNoContent && status != http.StatusNotModified && (status < 100 || status > 199)
+}
+
+// addVary adds the value to the Vary header if it isn't listed yet
+func addVary(header http.Header, value string) {
+	for _, v := range header.Values("Vary") {
+		for _, field := range strings.Split(v, ",") {
+			field = strings.TrimSpace(field)
+			if field == "*" || strings.EqualFold(field, value) {
+				return
+			}
+		}
+	}
+
+	header.Add("Vary", value)
+}
+
+// negotiateEncoding returns the encoder the Accept-Encoding header prefers,
+// ties are broken by the order of the encoders. Nil is returned if the
+// client doesn't accept any of them.
+func negotiateEncoding(accept string, encoders []ContentEncoder) ContentEncoder {
+	if accept == "" {
+		return nil
+	}
+
+	weights := make(map[string]float64)
+	wildcard := -1.0
+
+	for _, entry := range strings.Split(accept, ",") {
+		params := strings.Split(entry, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		weight := 1.0
+
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					weight = q
+				}
+			}
+		}
+
+		if coding == "*" {
+			wildcard = weight
+		} else {
+			weights[coding] = weight
+		}
+	}
+
+	var best ContentEncoder
+	bestWeight := 0.0
+
+	for _, encoder := range encoders {
+		weight, ok := weights[strings.ToLower(encoder.Encoding())]
+		if !ok {
+			weight = wildcard
+		}
+
+		if weight > bestWeight {
+			best, bestWeight = encoder, weight
+		}
+	}
+
+	return best
+}
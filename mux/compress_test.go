@@ -0,0 +1,246 @@
+package mux
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var compressTests = []struct {
+	description, requestURL, acceptEncoding, contentType string
+	bodySize                                             int
+	expectedEncoding, expectedVary                       string
+}{{
+	description:      "Testing: A large body is compressed with gzip.",
+	requestURL:       "/data",
+	acceptEncoding:   "gzip, deflate",
+	bodySize:         2048,
+	expectedEncoding: "gzip",
+	expectedVary:     "Accept-Encoding",
+}, {
+	description:      "Testing: The coding with the highest weight is used.",
+	requestURL:       "/data",
+	acceptEncoding:   "gzip;q=0.5, deflate",
+	bodySize:         2048,
+	expectedEncoding: "deflate",
+	expectedVary:     "Accept-Encoding",
+}, {
+	description:    "Testing: A body under the minimum size isn't compressed.",
+	requestURL:     "/data",
+	acceptEncoding: "gzip",
+	bodySize:       100,
+	expectedVary:   "Accept-Encoding",
+}, {
+	description:  "Testing: A body isn't compressed for clients that don't accept a coding.",
+	requestURL:   "/data",
+	bodySize:     2048,
+	expectedVary: "Accept-Encoding",
+}, {
+	description:    "Testing: A coding with a zero weight isn't used.",
+	requestURL:     "/data",
+	acceptEncoding: "gzip;q=0, br",
+	bodySize:       2048,
+	expectedVary:   "Accept-Encoding",
+}, {
+	description:      "Testing: A wildcard accepts the preferred coding.",
+	requestURL:       "/data",
+	acceptEncoding:   "*",
+	bodySize:         2048,
+	expectedEncoding: "gzip",
+	expectedVary:     "Accept-Encoding",
+}, {
+	description:    "Testing: Already compressed content types are skipped.",
+	requestURL:     "/data",
+	acceptEncoding: "gzip",
+	contentType:    "image/png",
+	bodySize:       2048,
+}, {
+	description:    "Testing: Routes can opt out of compression.",
+	requestURL:     "/raw",
+	acceptEncoding: "gzip",
+	bodySize:       2048,
+}}
+
+func TestCompress(t *testing.T) {
+	t.Log("Testing response bodies are compressed.")
+
+	for i, test := range compressTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		body := strings.Repeat("a", test.bodySize)
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			if test.contentType != "" {
+				w.Header().Set("Content-Type", test.contentType)
+			}
+			w.Write([]byte(body))
+		}
+
+		m := NewMux()
+		m.Use(Compress(CompressionConfig{}))
+		m.RegisterRoute("/data", handler)
+		must(m.RegisterRoute("/raw", handler)).DisableCompression()
+
+		r := httptest.NewRequest("GET", test.requestURL, nil)
+		if test.acceptEncoding != "" {
+			r.Header.Set("Accept-Encoding", test.acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		if encoding := w.Header().Get("Content-Encoding"); encoding != test.expectedEncoding {
+			t.Logf("[FAIL] :: Expected the encoding \"%s\" but got \"%s\".", test.expectedEncoding, encoding)
+			t.Fail()
+		}
+
+		if vary := w.Header().Get("Vary"); vary != test.expectedVary {
+			t.Logf("[FAIL] :: Expected Vary to be \"%s\" but got \"%s\".", test.expectedVary, vary)
+			t.Fail()
+		}
+
+		if decoded := decodeBody(w.Header().Get("Content-Encoding"), w.Body.Bytes()); decoded != body {
+			t.Logf("[FAIL] :: Expected the decoded body to have %d bytes but got %d.", len(body), len(decoded))
+			t.Fail()
+		}
+	}
+}
+
+func TestCompressRouteOptIn(t *testing.T) {
+	t.Log("Testing compression can be enabled for a single route.")
+
+	body := strings.Repeat("b", 2048)
+	m := NewMux()
+	must(m.RegisterRoute("/compressed", writeBody(body))).Use(Compress(CompressionConfig{MinSize: 10}))
+	m.RegisterRoute("/plain", writeBody(body))
+
+	for url, expected := range map[string]string{"/compressed": "gzip", "/plain": ""} {
+		r := httptest.NewRequest("GET", url, nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		if encoding := w.Header().Get("Content-Encoding"); encoding != expected {
+			t.Logf("[FAIL] :: Expected %s to have the encoding \"%s\" but got \"%s\".", url, expected, encoding)
+			t.Fail()
+		}
+	}
+}
+
+func TestCompressFlush(t *testing.T) {
+	t.Log("Testing flushing a compressed response sends the data so far.")
+
+	m := NewMux()
+	m.Use(Compress(CompressionConfig{}))
+
+	recorder := httptest.NewRecorder()
+
+	var flushed []byte
+	m.RegisterRoute("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte("data: one\n\n"))
+		w.(http.Flusher).Flush()
+
+		flushed = append(flushed, recorder.Body.Bytes()...)
+		w.Write([]byte("data: two\n\n"))
+	})
+
+	r := httptest.NewRequest("GET", "/events", nil)
+	r.Header.Set("Accept-Encoding", "gzip")
+
+	m.ServeHTTP(recorder, r)
+
+	if recorder.Header().Get("Content-Encoding") != "gzip" || !recorder.Flushed {
+		t.Logf("[FAIL] :: Expected a flushed gzip response but got %v.", recorder.Header())
+		t.Fail()
+	}
+
+	if partial := decodePartial(flushed); partial != "data: one\n\n" {
+		t.Logf("[FAIL] :: Expected the first event to be flushed but got \"%s\".", partial)
+		t.Fail()
+	}
+
+	if decoded := decodeBody("gzip", recorder.Body.Bytes()); decoded != "data: one\n\ndata: two\n\n" {
+		t.Logf("[FAIL] :: Expected both events but got \"%s\".", decoded)
+		t.Fail()
+	}
+}
+
+// upperEncoder is a ContentEncoder for tests that upper cases the body
+type upperEncoder struct{}
+
+func (upperEncoder) Encoding() string {
+	return "upper"
+}
+
+func (upperEncoder) NewWriter(w io.Writer) io.WriteCloser {
+	return upperWriter{w}
+}
+
+type upperWriter struct {
+	io.Writer
+}
+
+func (u upperWriter) Write(b []byte) (int, error) {
+	return u.Writer.Write(bytes.ToUpper(b))
+}
+
+func (u upperWriter) Close() error {
+	return nil
+}
+
+func TestCompressCustomEncoder(t *testing.T) {
+	t.Log("Testing custom content encoders can be used.")
+
+	m := NewMux()
+	m.Use(Compress(CompressionConfig{Encoders: []ContentEncoder{upperEncoder{}, GzipEncoder{}}, MinSize: 1}))
+	m.RegisterRoute("/data", writeBody("hello"))
+
+	r := httptest.NewRequest("GET", "/data", nil)
+	r.Header.Set("Accept-Encoding", "gzip, upper")
+	w := httptest.NewRecorder()
+
+	m.ServeHTTP(w, r)
+
+	if w.Header().Get("Content-Encoding") != "upper" || w.Body.String() != "HELLO\n" {
+		t.Logf("[FAIL] :: Expected the upper encoding but got \"%s\" \"%s\".", w.Header().Get("Content-Encoding"), w.Body.String())
+		t.Fail()
+	}
+}
+
+// decodeBody decodes a response body with the content coding
+func decodeBody(encoding string, body []byte) string {
+	var r io.Reader = bytes.NewReader(body)
+
+	switch encoding {
+	case "gzip":
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return ""
+		}
+		r = gr
+	case "deflate":
+		r = flate.NewReader(r)
+	}
+
+	decoded, _ := io.ReadAll(r)
+	return string(decoded)
+}
+
+// decodePartial decodes a gzip stream that hasn't been closed yet
+func decodePartial(body []byte) string {
+	gr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	decoded := make([]byte, 64)
+	n, _ := io.ReadAtLeast(gr, decoded, 1)
+
+	return string(decoded[:n])
+}
@@ -0,0 +1,142 @@
+package mux
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ConcurrencyLimitConfig configures a ConcurrencyLimiter
+type ConcurrencyLimitConfig struct {
+	// Name identifies the limiter in logs and metrics
+	Name string
+	// MaxInFlight is the number of requests served at once, it defaults
+	// to 1.
+	MaxInFlight int
+	// MaxQueue is the number of requests that wait for a free slot, the
+	// default of 0 sheds requests as soon as every slot is taken.
+	MaxQueue int
+	// QueueTimeout is how long a request waits in the queue before it is
+	// shed, 0 waits until a slot is free or the request is canceled.
+	QueueTimeout time.Duration
+}
+
+// ConcurrencyLimiter caps the number of requests served at once by the
+// routes it is used on, requests over the cap wait in a bounded queue. Use
+// one limiter on a route or group with Use(limiter.Middleware), using it on
+// several routes makes them share the cap.
+type ConcurrencyLimiter struct {
+	name    string
+	timeout time.Duration
+	slots   chan struct{}
+
+	mu       sync.Mutex
+	maxQueue int
+	queued   int
+	shed     uint64
+}
+
+// ConcurrencyStats is a snapshot of a ConcurrencyLimiter
+type ConcurrencyStats struct {
+	// InFlight is the number of requests being served
+	InFlight int
+	// Queued is the number of requests waiting for a slot
+	Queued int
+	// Shed is the number of requests that were rejected
+	Shed uint64
+}
+
+// NewConcurrencyLimiter returns a limiter for the configuration
+func NewConcurrencyLimiter(config ConcurrencyLimitConfig) *ConcurrencyLimiter {
+	if config.MaxInFlight < 1 {
+		config.MaxInFlight = 1
+	}
+
+	if config.MaxQueue < 0 {
+		config.MaxQueue = 0
+	}
+
+	return &ConcurrencyLimiter{
+		name:     config.Name,
+		timeout:  config.QueueTimeout,
+		slots:    make(chan struct{}, config.MaxInFlight),
+		maxQueue: config.MaxQueue,
+	}
+}
+
+// Middleware limits the requests served by the next handler, requests
+// that can't be queued or time out in the queue are shed with the 503
+// error handler.
+func (l *ConcurrencyLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if reason := l.acquire(r); reason != "" {
+			if state := stateFromRequest(r); state != nil && state.mux != nil {
+				state.mux.logRequest(r, LevelWarn, "Request was shed by the concurrency limiter", "limiter", l.name, "reason", reason)
+			}
+
+			Error(w, r, http.StatusServiceUnavailable)
+			return
+		}
+		defer l.release()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Stats returns the current state of the limiter
+func (l *ConcurrencyLimiter) Stats() ConcurrencyStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return ConcurrencyStats{InFlight: len(l.slots), Queued: l.queued, Shed: l.shed}
+}
+
+// acquire takes a slot for the request, waiting in the queue if every slot
+// is taken. The reason the request was shed is returned if it didn't get a
+// slot.
+func (l *ConcurrencyLimiter) acquire(r *http.Request) string {
+	select {
+	case l.slots <- struct{}{}:
+		return ""
+	default:
+	}
+
+	l.mu.Lock()
+	if l.queued >= l.maxQueue {
+		l.shed++
+		l.mu.Unlock()
+		return "queue full"
+	}
+	l.queued++
+	l.mu.Unlock()
+
+	var expired <-chan time.Time
+	if l.timeout > 0 {
+		timer := time.NewTimer(l.timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	reason := ""
+	select {
+	case l.slots <- struct{}{}:
+	case <-expired:
+		reason = "queue timeout"
+	case <-r.Context().Done():
+		reason = "request canceled"
+	}
+
+	l.mu.Lock()
+	l.queued--
+	if reason != "" {
+		l.shed++
+	}
+	l.mu.Unlock()
+
+	return reason
+}
+
+// release frees the slot of a request
+func (l *ConcurrencyLimiter) release() {
+	<-l.slots
+}
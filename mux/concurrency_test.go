@@ -0,0 +1,130 @@
+package mux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var concurrencyTests = []struct {
+	description      string
+	config           ConcurrencyLimitConfig
+	expectedStatuses []int
+}{{
+	description:      "Testing: Requests over the cap are shed without a queue.",
+	config:           ConcurrencyLimitConfig{MaxInFlight: 1},
+	expectedStatuses: []int{200, 503},
+}, {
+	description:      "Testing: Queued requests are served when a slot is free.",
+	config:           ConcurrencyLimitConfig{MaxInFlight: 1, MaxQueue: 1},
+	expectedStatuses: []int{200, 200, 503},
+}, {
+	description:      "Testing: Queued requests are shed when the queue timeout expires.",
+	config:           ConcurrencyLimitConfig{MaxInFlight: 1, MaxQueue: 1, QueueTimeout: 10 * time.Millisecond},
+	expectedStatuses: []int{200, 503},
+}, {
+	description:      "Testing: Requests under the cap are served at once.",
+	config:           ConcurrencyLimitConfig{MaxInFlight: 2},
+	expectedStatuses: []int{200, 200},
+}}
+
+func TestConcurrencyLimit(t *testing.T) {
+	t.Log("Testing the number of requests served at once is limited.")
+
+	for i, test := range concurrencyTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		limiter := NewConcurrencyLimiter(test.config)
+		release := make(chan struct{})
+
+		m := NewMux()
+		m.RegisterErrorHandler(http.StatusServiceUnavailable, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("busy"))
+		})
+		must(m.RegisterRoute("/export", func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		})).Use(limiter.Middleware)
+
+		statuses := make([]chan int, len(test.expectedStatuses))
+		for j := range statuses {
+			statuses[j] = make(chan int, 1)
+
+			go func(status chan int) {
+				w := httptest.NewRecorder()
+				m.ServeHTTP(w, httptest.NewRequest("GET", "/export", nil))
+				status <- w.Code
+			}(statuses[j])
+
+			waitFor(func() bool {
+				stats := limiter.Stats()
+				return stats.InFlight+stats.Queued+int(stats.Shed) == j+1
+			})
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		close(release)
+
+		for j, status := range statuses {
+			if got := <-status; got != test.expectedStatuses[j] {
+				t.Logf("[FAIL] :: Expected request %d to get %d but got %d.", j+1, test.expectedStatuses[j], got)
+				t.Fail()
+			}
+		}
+	}
+}
+
+func TestConcurrencyMetrics(t *testing.T) {
+	t.Log("Testing the concurrency limiter is reported with the metrics.")
+
+	metrics := NewMetrics(MetricsConfig{})
+	limiter := NewConcurrencyLimiter(ConcurrencyLimitConfig{Name: "exports", MaxInFlight: 1, MaxQueue: 1})
+	metrics.AddLimiter(limiter)
+
+	release := make(chan struct{})
+	m := NewMux()
+	m.Group("/exports").Use(limiter.Middleware).RegisterRoute("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+
+	done := make(chan struct{}, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/exports/1", nil))
+			done <- struct{}{}
+		}()
+	}
+
+	waitFor(func() bool {
+		stats := limiter.Stats()
+		return stats.InFlight == 1 && stats.Queued == 1 && stats.Shed == 1
+	})
+
+	w := httptest.NewRecorder()
+	metrics.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	close(release)
+	for i := 0; i < 3; i++ {
+		<-done
+	}
+
+	for _, line := range []string{
+		`http_concurrency_in_flight{limiter="exports"} 1`,
+		`http_concurrency_queue_depth{limiter="exports"} 1`,
+		`http_concurrency_shed_total{limiter="exports"} 1`,
+	} {
+		if !strings.Contains(w.Body.String(), line+"\n") {
+			t.Logf("[FAIL] :: Expected the line \"%s\" in:\n%s", line, w.Body.String())
+			t.Fail()
+		}
+	}
+}
+
+// waitFor polls the condition until it is true or a second has passed
+func waitFor(condition func() bool) {
+	for deadline := time.Now().Add(time.Second); !condition() && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+}
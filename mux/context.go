@@ -0,0 +1,65 @@
+package mux
+
+import (
+	"context"
+	"net/http"
+)
+
+// contextKey is the type for values the mux stores in the request context,
+// it is unexported so the keys can't collide with keys from other packages.
+type contextKey int
+
+const (
+	stateKey contextKey = iota
+	versionKey
+	requestIDKey
+	traceKey
+	errorKey
+)
+
+// requestState holds what the mux learns about a request while serving it,
+// it is stored in the request context as a pointer so that the route can be
+// read after it has been selected by code that saw the request earlier.
+type requestState struct {
+	mux       *Mux
+	route     *Route
+	requestID string
+	// parent is the state of the mux that mounted this one, when the mux is
+	// registered as a handler on another mux.
+	parent *requestState
+	// endInFlight ends the request in the in flight gauge, a handler that
+	// outlives its time limit takes it over so the gauge counts it until it
+	// returns.
+	endInFlight func()
+}
+
+// withState returns a shallow copy of the request that carries the state
+func withState(r *http.Request, state *requestState) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), stateKey, state))
+}
+
+// stateFromRequest returns the state of the request, or nil if the request
+// isn't being served through a Mux.
+func stateFromRequest(r *http.Request) *requestState {
+	state, _ := r.Context().Value(stateKey).(*requestState)
+	return state
+}
+
+// releaseInFlight ends the request in the in flight gauge unless the
+// handler took that over.
+func (s *requestState) releaseInFlight() {
+	if s.endInFlight != nil {
+		s.endInFlight()
+		s.endInFlight = nil
+	}
+}
+
+// routeFromRequest returns the route that was selected to serve the request,
+// or nil if no route has been selected.
+func routeFromRequest(r *http.Request) *Route {
+	if state := stateFromRequest(r); state != nil {
+		return state.route
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package mux
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS configures Cross-Origin Resource Sharing for the mux or for a route.
+// Requests from an origin that isn't allowed get a 403 from the registered
+// error handler.
+type CORS struct {
+	// AllowedOrigins are the origins that may make requests, entries can be
+	// exact such as "https://example.com", contain a wildcard such as
+	// "https://*.example.com" or be "*" to allow every origin.
+	AllowedOrigins []string
+	// AllowOriginFunc is called for origins that don't match AllowedOrigins
+	// and allows the origin if it returns true.
+	AllowOriginFunc func(origin string) bool
+	// AllowedMethods are the methods returned for preflight requests, if
+	// empty the methods the route is restricted to are used.
+	AllowedMethods []string
+	// AllowedHeaders are the request headers the client may send, "*"
+	// allows every header the preflight request asks for.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers the client may read.
+	ExposedHeaders []string
+	// AllowCredentials lets the client send cookies and authorization.
+	AllowCredentials bool
+	// MaxAge is how long the client may cache the preflight response.
+	MaxAge time.Duration
+}
+
+// CORS sets the CORS configuration used by every route that doesn't set
+// its own configuration.
+func (m *Mux) CORS(c *CORS) {
+	m.cors = c
+}
+
+// CORS sets the CORS configuration for the route, overriding the
+// configuration of the mux.
+func (r *Route) CORS(c *CORS) *Route {
+	r.cors = c
+
+	return r
+}
+
+// corsConfig returns the CORS configuration that applies to the route
+func (m *Mux) corsConfig(route *Route) *CORS {
+	if route.cors != nil {
+		return route.cors
+	}
+
+	return m.cors
+}
+
+// isPreflight reports if the request is a CORS preflight request
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions &&
+		r.Header.Get("Origin") != "" &&
+		r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// servePreflight answers a preflight request from the configuration of the
+// routes matching the path, the allowed methods are taken from those routes.
+// It returns false if no route matching the path has a CORS configuration.
+func (m *Mux) servePreflight(w http.ResponseWriter, r *http.Request) bool {
+	var config *CORS
+	var allowed []string
+	unrestricted := false
+
+	requestPath := m.requestPath(r)
+	for _, route := range m.routes {
+		if m.matchPath(route, requestPath) != fullMatch {
+			continue
+		}
+
+		c := m.corsConfig(route)
+		if c == nil {
+			continue
+		}
+
+		if config == nil {
+			config = c
+		}
+
+		if len(route.allowedMethods) == 0 {
+			unrestricted = true
+		}
+		allowed = appendUnique(allowed, route.allowMethods()...)
+	}
+
+	if config == nil {
+		return false
+	}
+
+	origin := r.Header.Get("Origin")
+	if !config.allowsOrigin(origin) {
+		m.serveError(w, r, http.StatusForbidden)
+		return true
+	}
+
+	if len(config.AllowedMethods) > 0 {
+		allowed, unrestricted = config.AllowedMethods, false
+	}
+
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	if unrestricted {
+		allowed = appendUnique(allowed, method)
+	}
+
+	if !containsString(allowed, method) {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		m.serveError(w, r, http.StatusMethodNotAllowed)
+		return true
+	}
+
+	h := w.Header()
+	config.setOriginHeaders(h, origin)
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	h.Set("Access-Control-Allow-Methods", strings.Join(allowed, ", "))
+
+	if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		if containsString(config.AllowedHeaders, "*") {
+			h.Set("Access-Control-Allow-Headers", requested)
+		} else if len(config.AllowedHeaders) > 0 {
+			h.Set("Access-Control-Allow-Headers", strings.Join(config.AllowedHeaders, ", "))
+		}
+	}
+
+	if config.MaxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(config.MaxAge.Seconds())))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// applyCORS sets the CORS headers for a request to the route. It returns
+// false, after responding with a 403, if the origin isn't allowed.
+func (m *Mux) applyCORS(route *Route, w http.ResponseWriter, r *http.Request) bool {
+	config := m.corsConfig(route)
+	origin := r.Header.Get("Origin")
+
+	if config == nil || origin == "" || isSameOrigin(r, origin) {
+		return true
+	}
+
+	if !config.allowsOrigin(origin) {
+		m.serveError(w, r, http.StatusForbidden)
+		return false
+	}
+
+	h := w.Header()
+	config.setOriginHeaders(h, origin)
+
+	if len(config.ExposedHeaders) > 0 {
+		h.Set("Access-Control-Expose-Headers", strings.Join(config.ExposedHeaders, ", "))
+	}
+
+	return true
+}
+
+// setOriginHeaders sets the headers that allow the origin
+func (c *CORS) setOriginHeaders(h http.Header, origin string) {
+	if containsString(c.AllowedOrigins, "*") && !c.AllowCredentials {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+		h.Add("Vary", "Origin")
+	}
+
+	if c.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// allowsOrigin checks the origin against the allowed origins
+func (c *CORS) allowsOrigin(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+
+		if i := strings.Index(allowed, "*"); i >= 0 {
+			prefix, suffix := allowed[:i], allowed[i+1:]
+			if len(origin) >= len(prefix)+len(suffix) &&
+				strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		}
+	}
+
+	return c.AllowOriginFunc != nil && c.AllowOriginFunc(origin)
+}
+
+// isSameOrigin reports if the origin is the origin the request was made to
+func isSameOrigin(r *http.Request, origin string) bool {
+	return strings.EqualFold(origin, requestScheme(r)+"://"+r.Host)
+}
+
+// containsString reports if the slice contains the value
+func containsString(slice []string, value string) bool {
+	for _, s := range slice {
+		if s == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,115 @@
+package mux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var corsTests = []struct {
+	description, method string
+	headers             map[string]string
+	routeConfig         *CORS
+	expectedCode        int
+	expectedHeaders     map[string]string
+}{{
+	description:  "Testing: A preflight request from an allowed origin is answered with the route's methods.",
+	method:       "OPTIONS",
+	headers:      map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "POST"},
+	expectedCode: http.StatusNoContent,
+	expectedHeaders: map[string]string{
+		"Access-Control-Allow-Origin":  "https://app.example.com",
+		"Access-Control-Allow-Methods": "GET, HEAD, POST",
+		"Access-Control-Max-Age":       "600",
+	},
+}, {
+	description:     "Testing: A preflight request from an origin that isn't allowed returns 403.",
+	method:          "OPTIONS",
+	headers:         map[string]string{"Origin": "https://evil.com", "Access-Control-Request-Method": "POST"},
+	expectedCode:    http.StatusForbidden,
+	expectedHeaders: map[string]string{"Access-Control-Allow-Origin": ""},
+}, {
+	description:     "Testing: A preflight request for a method the route doesn't allow returns 405.",
+	method:          "OPTIONS",
+	headers:         map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "DELETE"},
+	expectedCode:    http.StatusMethodNotAllowed,
+	expectedHeaders: map[string]string{"Allow": "GET, HEAD, POST"},
+}, {
+	description:     "Testing: A preflight request for HEAD is allowed when the route allows GET.",
+	method:          "OPTIONS",
+	headers:         map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "HEAD"},
+	expectedCode:    http.StatusNoContent,
+	expectedHeaders: map[string]string{"Access-Control-Allow-Methods": "GET, HEAD, POST"},
+}, {
+	description:  "Testing: A request from an origin matching a wildcard gets the CORS headers.",
+	method:       "GET",
+	headers:      map[string]string{"Origin": "https://app.example.com"},
+	expectedCode: http.StatusOK,
+	expectedHeaders: map[string]string{
+		"Access-Control-Allow-Origin":   "https://app.example.com",
+		"Access-Control-Expose-Headers": "X-Total-Count",
+	},
+}, {
+	description:     "Testing: A request from an origin that isn't allowed returns 403.",
+	method:          "GET",
+	headers:         map[string]string{"Origin": "https://evil.com"},
+	expectedCode:    http.StatusForbidden,
+	expectedHeaders: map[string]string{"Access-Control-Allow-Origin": ""},
+}, {
+	description:     "Testing: A request from the same origin isn't checked.",
+	method:          "GET",
+	headers:         map[string]string{"Origin": "http://example.com"},
+	expectedCode:    http.StatusOK,
+	expectedHeaders: map[string]string{"Access-Control-Allow-Origin": ""},
+}, {
+	description:  "Testing: A route configuration overrides the mux configuration.",
+	method:       "GET",
+	headers:      map[string]string{"Origin": "https://evil.com"},
+	routeConfig:  &CORS{AllowOriginFunc: func(origin string) bool { return origin == "https://evil.com" }, AllowCredentials: true},
+	expectedCode: http.StatusOK,
+	expectedHeaders: map[string]string{
+		"Access-Control-Allow-Origin":      "https://evil.com",
+		"Access-Control-Allow-Credentials": "true",
+	},
+}}
+
+func TestCORS(t *testing.T) {
+	t.Log("Testing CORS preflight and actual requests.")
+
+	for i, test := range corsTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		m := NewMux()
+		m.CORS(&CORS{
+			AllowedOrigins: []string{"https://*.example.com"},
+			ExposedHeaders: []string{"X-Total-Count"},
+			MaxAge:         10 * time.Minute,
+		})
+
+		route := must(m.RegisterRoute("/items", writeBody("items"))).Methods("GET", "POST")
+		if test.routeConfig != nil {
+			route.CORS(test.routeConfig)
+		}
+
+		r := httptest.NewRequest(test.method, "http://example.com/items", nil)
+		for k, v := range test.headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		if w.Code != test.expectedCode {
+			t.Logf("[FAIL] :: Expected status code %d but got status code %d.", test.expectedCode, w.Code)
+			t.Fail()
+		}
+
+		for k, v := range test.expectedHeaders {
+			if header := w.Header().Get(k); header != v {
+				t.Logf("[FAIL] :: Expected %s header \"%s\" but got \"%s\".", k, v, header)
+				t.Fail()
+			}
+		}
+	}
+}
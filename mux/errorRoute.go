@@ -0,0 +1,125 @@
+package mux
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ErrorRouteFunc is a handler that returns an error instead of writing
+// the error response itself, the mux maps the error to a status code and
+// calls the error handler registered for it. The handler shouldn't write
+// to the response before returning an error.
+type ErrorRouteFunc func(w http.ResponseWriter, r *http.Request) error
+
+// ErrorMapper maps an error returned by an ErrorRouteFunc to a status code
+type ErrorMapper func(err error) int
+
+// HTTPError is an error with the status code it should be answered with,
+// Code and Message are meant for the client and are available to error
+// handlers through GetError.
+type HTTPError struct {
+	Status  int
+	Code    string
+	Message string
+	Err     error
+}
+
+// Error returns the message, or the status text if there is no message,
+// followed by the wrapped error.
+func (e *HTTPError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.Status)
+	}
+
+	if e.Err != nil {
+		return msg + ": " + e.Err.Error()
+	}
+
+	return msg
+}
+
+// Unwrap returns the wrapped error
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// DefaultErrorMapper uses the status of an HTTPError in the error chain,
+// 413 for *http.MaxBytesError, 503 for an exceeded deadline and 500 for
+// every other error.
+func DefaultErrorMapper(err error) int {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.Status != 0 {
+		return httpErr.Status
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return http.StatusRequestEntityTooLarge
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusInternalServerError
+}
+
+// ErrorMapper sets the function that maps errors returned by error routes
+// to status codes, DefaultErrorMapper is used until it's set.
+func (m *Mux) ErrorMapper(mapper ErrorMapper) {
+	m.errorMapper = mapper
+}
+
+// RegisterErrorRoute adds an ErrorRouteFunc to the multiplexer for the
+// route specified, see RegisterRoute. Errors returned by the handler are
+// mapped to a status code and answered with the registered error handler.
+func (m *Mux) RegisterErrorRoute(route string, handler ErrorRouteFunc) (*Route, error) {
+	gh := gowtHandler{handler: errorRoute{mux: m, handler: handler}}
+
+	return m.register(route, gh)
+}
+
+// RegisterErrorRoute adds an ErrorRouteFunc to the multiplexer for the
+// route under the group's prefix, see Mux.RegisterErrorRoute.
+func (g *Group) RegisterErrorRoute(route string, handler ErrorRouteFunc) (*Route, error) {
+	return g.register(route, gowtHandler{handler: errorRoute{mux: g.mux, handler: handler}})
+}
+
+// GetError returns the error an error route returned, error handlers use
+// it to describe the error. Nil is returned for other requests.
+func GetError(r *http.Request) error {
+	err, _ := r.Context().Value(errorKey).(error)
+	return err
+}
+
+// errorRoute serves an ErrorRouteFunc
+type errorRoute struct {
+	mux     *Mux
+	handler ErrorRouteFunc
+}
+
+// ServeHTTP calls the handler and answers an error it returns with the
+// error handler for the status the error maps to.
+func (er errorRoute) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	err := er.handler(w, r)
+	if err == nil {
+		return
+	}
+
+	mapper := er.mux.errorMapper
+	if mapper == nil {
+		mapper = DefaultErrorMapper
+	}
+
+	status := mapper(err)
+
+	level := LevelDebug
+	if status >= http.StatusInternalServerError {
+		level = LevelError
+	}
+	er.mux.logRequest(r, level, "Route returned an error", "status", status, "error", err.Error())
+
+	er.mux.serveError(w, r.WithContext(context.WithValue(r.Context(), errorKey, err)), status)
+}
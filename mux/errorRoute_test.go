@@ -0,0 +1,99 @@
+package mux
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// errNotFound is returned by the error route tests for a missing record
+var errNotFound = errors.New("record not found")
+
+var errorRouteTests = []struct {
+	description    string
+	err            error
+	mapper         ErrorMapper
+	expectedStatus int
+	expectedBody   string
+}{{
+	description:    "Testing: A nil error leaves the response to the handler.",
+	expectedStatus: http.StatusOK,
+	expectedBody:   "ok",
+}, {
+	description:    "Testing: An HTTPError is answered with the handler for its status.",
+	err:            &HTTPError{Status: http.StatusConflict, Code: "duplicate", Message: "Name is taken"},
+	expectedStatus: http.StatusConflict,
+	expectedBody:   "409 duplicate: Name is taken",
+}, {
+	description:    "Testing: A wrapped HTTPError is found with errors.As.",
+	err:            fmt.Errorf("creating user: %w", &HTTPError{Status: http.StatusConflict, Code: "duplicate"}),
+	expectedStatus: http.StatusConflict,
+	expectedBody:   "409 duplicate: Conflict",
+}, {
+	description:    "Testing: Other errors are answered with a 500.",
+	err:            errors.New("database is down"),
+	expectedStatus: http.StatusInternalServerError,
+	expectedBody:   "Internal Server Error\n",
+}, {
+	description: "Testing: A custom mapper maps errors to status codes.",
+	err:         fmt.Errorf("loading: %w", errNotFound),
+	mapper: func(err error) int {
+		if errors.Is(err, errNotFound) {
+			return http.StatusNotFound
+		}
+		return DefaultErrorMapper(err)
+	},
+	expectedStatus: http.StatusNotFound,
+	expectedBody:   "not found: loading: record not found",
+}}
+
+func TestErrorRoutes(t *testing.T) {
+	t.Log("Testing errors returned by handlers are mapped to error handlers.")
+
+	for i, test := range errorRouteTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		logger := NewTestLogger()
+		m := NewMux()
+		m.RegisterLogger(logger)
+		if test.mapper != nil {
+			m.ErrorMapper(test.mapper)
+		}
+
+		m.RegisterErrorHandler(http.StatusConflict, func(w http.ResponseWriter, r *http.Request) {
+			var httpErr *HTTPError
+			errors.As(GetError(r), &httpErr)
+
+			w.WriteHeader(http.StatusConflict)
+			fmt.Fprintf(w, "409 %s: %s", httpErr.Code, httpErr.Error())
+		})
+		m.RegisterErrorHandler(http.StatusNotFound, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, "not found: %v", GetError(r))
+		})
+
+		m.RegisterErrorRoute("/users", func(w http.ResponseWriter, r *http.Request) error {
+			if test.err != nil {
+				return test.err
+			}
+
+			w.Write([]byte("ok"))
+			return nil
+		})
+
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("POST", "/users", nil))
+
+		if w.Code != test.expectedStatus || w.Body.String() != test.expectedBody {
+			t.Logf("[FAIL] :: Expected %d \"%s\" but got %d \"%s\".", test.expectedStatus, test.expectedBody, w.Code, w.Body.String())
+			t.Fail()
+		}
+
+		if server := test.expectedStatus >= 500; server != (len(logger.EntriesAt(LevelError)) == 1) {
+			t.Logf("[FAIL] :: Expected server errors to be logged but got %v.", logger.Entries())
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package mux
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ETagConfig configures the ETag middleware
+type ETagConfig struct {
+	// Weak makes the computed ETags weak validators, use it when the body
+	// can change without the resource changing, e.g. when it's compressed.
+	Weak bool
+	// Validator returns the current ETag and modification time of the
+	// resource the request is for, either can be empty. When it's set the
+	// preconditions are checked before the handler runs, so writes such as
+	// PUT are only made if the client's copy is current, and responses
+	// aren't buffered.
+	Validator func(r *http.Request) (etag string, modified time.Time)
+}
+
+// ETag returns middleware that handles conditional requests. GET and HEAD
+// responses are buffered and given an ETag computed from the body, unless
+// the handler set an ETag header itself, and are answered with 304 Not
+// Modified when If-None-Match or If-Modified-Since shows the client's copy
+// is current. Failed If-Match and If-Unmodified-Since preconditions are
+// answered with the 412 error handler.
+//
+// Handlers of routes without a Validator can call CheckPreconditions
+// before changing a resource.
+func ETag(config ETagConfig) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if config.Validator != nil {
+				etag, modified := config.Validator(r)
+				if !CheckPreconditions(w, r, etag, modified) {
+					return
+				}
+
+				if etag != "" {
+					w.Header().Set("ETag", etag)
+				}
+				if !modified.IsZero() {
+					w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+				}
+
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ew := &etagWriter{ResponseWriter: w}
+			next.ServeHTTP(ew, r)
+
+			if ew.streaming {
+				return
+			}
+
+			if ew.status == 0 {
+				ew.status = http.StatusOK
+			}
+
+			header := w.Header()
+			if ew.status == http.StatusOK {
+				if header.Get("ETag") == "" {
+					header.Set("ETag", computeETag(ew.buf, config.Weak))
+				}
+
+				modified, _ := http.ParseTime(header.Get("Last-Modified"))
+				if !CheckPreconditions(w, r, header.Get("ETag"), modified) {
+					return
+				}
+			}
+
+			w.WriteHeader(ew.status)
+			w.Write(ew.buf)
+		})
+	}
+}
+
+// CheckPreconditions evaluates the conditional headers of the request
+// against the current ETag and modification time of the resource, as
+// described in RFC 9110. If the request shouldn't be served it responds
+// with 304 Not Modified for GET and HEAD or the 412 error handler
+// otherwise, and returns false.
+func CheckPreconditions(w http.ResponseWriter, r *http.Request, etag string, modified time.Time) bool {
+	switch evaluatePreconditions(r, etag, modified) {
+	case http.StatusNotModified:
+		header := w.Header()
+		if etag != "" {
+			header.Set("ETag", etag)
+		}
+		if !modified.IsZero() {
+			header.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+		}
+		header.Del("Content-Type")
+		header.Del("Content-Length")
+
+		w.WriteHeader(http.StatusNotModified)
+		return false
+	case http.StatusPreconditionFailed:
+		Error(w, r, http.StatusPreconditionFailed)
+		return false
+	}
+
+	return true
+}
+
+// evaluatePreconditions returns 304 or 412 if a precondition fails and 0
+// if the request should be served.
+func evaluatePreconditions(r *http.Request, etag string, modified time.Time) int {
+	modified = modified.Truncate(time.Second)
+	safe := r.Method == http.MethodGet || r.Method == http.MethodHead
+
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" {
+		if !matchesETag(ifMatch, etag, true) {
+			return http.StatusPreconditionFailed
+		}
+	} else if t, err := http.ParseTime(r.Header.Get("If-Unmodified-Since")); err == nil && !modified.IsZero() {
+		if modified.After(t) {
+			return http.StatusPreconditionFailed
+		}
+	}
+
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if matchesETag(ifNoneMatch, etag, false) {
+			if safe {
+				return http.StatusNotModified
+			}
+
+			return http.StatusPreconditionFailed
+		}
+	} else if t, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && safe && !modified.IsZero() {
+		if !modified.After(t) {
+			return http.StatusNotModified
+		}
+	}
+
+	return 0
+}
+
+// matchesETag reports if the ETag is in the list of a conditional header,
+// strong comparison is used for If-Match and weak comparison otherwise.
+// A "*" matches any current ETag.
+func matchesETag(list, etag string, strong bool) bool {
+	if etag == "" {
+		return false
+	}
+
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+
+	for _, candidate := range parseETags(list) {
+		if strong && (isWeakETag(candidate) || isWeakETag(etag)) {
+			continue
+		}
+
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseETags splits a list of entity tags, the opaque tags can contain
+// commas so the quotes are followed.
+func parseETags(list string) []string {
+	var etags []string
+
+	for list != "" {
+		list = strings.TrimLeft(list, " \t,")
+
+		start := 0
+		if strings.HasPrefix(list, "W/") {
+			start = 2
+		}
+
+		if len(list) <= start || list[start] != '"' {
+			break
+		}
+
+		end := strings.IndexByte(list[start+1:], '"')
+		if end < 0 {
+			break
+		}
+
+		end += start + 2
+		etags = append(etags, list[:end])
+		list = list[end:]
+	}
+
+	return etags
+}
+
+// isWeakETag reports if the entity tag is a weak validator
+func isWeakETag(etag string) bool {
+	return strings.HasPrefix(etag, "W/")
+}
+
+// computeETag returns an entity tag from the hash of the body
+func computeETag(body []byte, weak bool) string {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	if weak {
+		return "W/" + etag
+	}
+
+	return etag
+}
+
+// etagWriter buffers a response so its ETag can be computed, a handler
+// that flushes streams the rest of the response without an ETag.
+type etagWriter struct {
+	http.ResponseWriter
+
+	status    int
+	buf       []byte
+	streaming bool
+}
+
+// WriteHeader records the status code while the response is buffered,
+// informational status codes are passed on.
+func (ew *etagWriter) WriteHeader(code int) {
+	if ew.streaming || (code >= 100 && code <= 199 && code != http.StatusSwitchingProtocols) {
+		ew.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	if ew.status == 0 {
+		ew.status = code
+	}
+}
+
+// Write buffers the body, or writes it through once the response streams
+func (ew *etagWriter) Write(b []byte) (int, error) {
+	if ew.streaming {
+		return ew.ResponseWriter.Write(b)
+	}
+
+	if ew.status == 0 {
+		ew.status = http.StatusOK
+	}
+
+	ew.buf = append(ew.buf, b...)
+
+	return len(b), nil
+}
+
+// Flush stops buffering and sends the response so far
+func (ew *etagWriter) Flush() {
+	if !ew.streaming {
+		ew.streaming = true
+
+		if ew.status == 0 {
+			ew.status = http.StatusOK
+		}
+
+		ew.ResponseWriter.WriteHeader(ew.status)
+		ew.ResponseWriter.Write(ew.buf)
+		ew.buf = nil
+	}
+
+	if f, ok := ew.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the wrapped response writer for http.ResponseController
+func (ew *etagWriter) Unwrap() http.ResponseWriter {
+	return ew.ResponseWriter
+}
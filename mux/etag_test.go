@@ -0,0 +1,196 @@
+package mux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// etagTestModified is the modification time of the resources in the ETag tests
+var etagTestModified = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+var etagTests = []struct {
+	description, method, requestURL string
+	headers                         map[string]string
+	expectedStatus                  int
+	expectedETag                    string
+}{{
+	description:    "Testing: A response gets an ETag computed from its body.",
+	method:         "GET",
+	requestURL:     "/computed",
+	expectedStatus: http.StatusOK,
+	expectedETag:   computeETag([]byte("computed\n"), false),
+}, {
+	description:    "Testing: A matching If-None-Match gets a 304.",
+	method:         "GET",
+	requestURL:     "/computed",
+	headers:        map[string]string{"If-None-Match": `"other", ` + computeETag([]byte("computed\n"), false)},
+	expectedStatus: http.StatusNotModified,
+	expectedETag:   computeETag([]byte("computed\n"), false),
+}, {
+	description:    "Testing: If-None-Match uses weak comparison.",
+	method:         "GET",
+	requestURL:     "/supplied",
+	headers:        map[string]string{"If-None-Match": `W/"v1"`},
+	expectedStatus: http.StatusNotModified,
+	expectedETag:   `"v1"`,
+}, {
+	description:    "Testing: A different If-None-Match gets the response.",
+	method:         "GET",
+	requestURL:     "/supplied",
+	headers:        map[string]string{"If-None-Match": `"v0"`},
+	expectedStatus: http.StatusOK,
+	expectedETag:   `"v1"`,
+}, {
+	description:    "Testing: An unchanged If-Modified-Since gets a 304.",
+	method:         "GET",
+	requestURL:     "/supplied",
+	headers:        map[string]string{"If-Modified-Since": etagTestModified.Format(http.TimeFormat)},
+	expectedStatus: http.StatusNotModified,
+	expectedETag:   `"v1"`,
+}, {
+	description:    "Testing: If-Modified-Since is ignored when If-None-Match is sent.",
+	method:         "GET",
+	requestURL:     "/supplied",
+	headers:        map[string]string{"If-None-Match": `"v0"`, "If-Modified-Since": etagTestModified.Format(http.TimeFormat)},
+	expectedStatus: http.StatusOK,
+	expectedETag:   `"v1"`,
+}, {
+	description:    "Testing: Weak ETags can be computed.",
+	method:         "GET",
+	requestURL:     "/weak",
+	expectedStatus: http.StatusOK,
+	expectedETag:   computeETag([]byte("weak\n"), true),
+}, {
+	description:    "Testing: A write with a matching If-Match is served.",
+	method:         "PUT",
+	requestURL:     "/validated",
+	headers:        map[string]string{"If-Match": `"v2"`},
+	expectedStatus: http.StatusNoContent,
+	expectedETag:   `"v2"`,
+}, {
+	description:    "Testing: A write with a stale If-Match gets the 412 error handler.",
+	method:         "PUT",
+	requestURL:     "/validated",
+	headers:        map[string]string{"If-Match": `"v1"`},
+	expectedStatus: http.StatusPreconditionFailed,
+}, {
+	description:    "Testing: If-Match uses strong comparison.",
+	method:         "PUT",
+	requestURL:     "/validated",
+	headers:        map[string]string{"If-Match": `W/"v2"`},
+	expectedStatus: http.StatusPreconditionFailed,
+}, {
+	description:    "Testing: A write with a stale If-Unmodified-Since gets the 412 error handler.",
+	method:         "PUT",
+	requestURL:     "/validated",
+	headers:        map[string]string{"If-Unmodified-Since": etagTestModified.Add(-time.Hour).Format(http.TimeFormat)},
+	expectedStatus: http.StatusPreconditionFailed,
+}, {
+	description:    "Testing: A write with If-None-Match * gets a 412 for an existing resource.",
+	method:         "PUT",
+	requestURL:     "/validated",
+	headers:        map[string]string{"If-None-Match": "*"},
+	expectedStatus: http.StatusPreconditionFailed,
+}}
+
+func TestETag(t *testing.T) {
+	t.Log("Testing ETags and conditional requests.")
+
+	for i, test := range etagTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		m := NewMux()
+		m.RegisterErrorHandler(http.StatusPreconditionFailed, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			w.Write([]byte("stale"))
+		})
+
+		must(m.RegisterRoute("/computed", writeBody("computed"))).Use(ETag(ETagConfig{}))
+		must(m.RegisterRoute("/weak", writeBody("weak"))).Use(ETag(ETagConfig{Weak: true}))
+		must(m.RegisterRoute("/supplied", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Last-Modified", etagTestModified.Format(http.TimeFormat))
+			w.Write([]byte("supplied"))
+		})).Use(ETag(ETagConfig{}))
+		must(m.RegisterRoute("/validated", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})).Use(ETag(ETagConfig{Validator: func(r *http.Request) (string, time.Time) {
+			return `"v2"`, etagTestModified
+		}}))
+
+		r := httptest.NewRequest(test.method, test.requestURL, nil)
+		for key, value := range test.headers {
+			r.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		if w.Code != test.expectedStatus {
+			t.Logf("[FAIL] :: Expected the status %d but got %d.", test.expectedStatus, w.Code)
+			t.Fail()
+		}
+
+		if etag := w.Header().Get("ETag"); etag != test.expectedETag {
+			t.Logf("[FAIL] :: Expected the ETag %s but got %s.", test.expectedETag, etag)
+			t.Fail()
+		}
+
+		if w.Code == http.StatusNotModified && w.Body.Len() != 0 {
+			t.Logf("[FAIL] :: Expected a 304 without a body but got \"%s\".", w.Body.String())
+			t.Fail()
+		}
+
+		if w.Code == http.StatusPreconditionFailed && w.Body.String() != "stale" {
+			t.Logf("[FAIL] :: Expected the 412 error handler but got \"%s\".", w.Body.String())
+			t.Fail()
+		}
+	}
+}
+
+var parseETagsTests = []struct {
+	description, list string
+	expected          []string
+}{{
+	description: "Testing: A single ETag is parsed.",
+	list:        `"abc"`,
+	expected:    []string{`"abc"`},
+}, {
+	description: "Testing: Weak and strong ETags are parsed from a list.",
+	list:        ` W/"a", "b" ,"c"`,
+	expected:    []string{`W/"a"`, `"b"`, `"c"`},
+}, {
+	description: "Testing: Commas inside an ETag are kept.",
+	list:        `"a,b", "c"`,
+	expected:    []string{`"a,b"`, `"c"`},
+}, {
+	description: "Testing: Parsing stops at a malformed ETag.",
+	list:        `"a", b`,
+	expected:    []string{`"a"`},
+}}
+
+func TestParseETags(t *testing.T) {
+	t.Log("Testing entity tag lists are parsed.")
+
+	for i, test := range parseETagsTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		etags := parseETags(test.list)
+
+		if len(etags) != len(test.expected) {
+			t.Logf("[FAIL] :: Expected %v but got %v.", test.expected, etags)
+			t.Fail()
+			continue
+		}
+
+		for j := range etags {
+			if etags[j] != test.expected[j] {
+				t.Logf("[FAIL] :: Expected %v but got %v.", test.expected, etags)
+				t.Fail()
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package mux
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Group is a set of routes that share a path prefix and middleware, it is
+// created with Mux.Group and routes are registered on it like on the mux.
+type Group struct {
+	mux           *Mux
+	parent        *Group
+	prefix        string
+	middleware    []Middleware
+	errorHandlers map[int]http.HandlerFunc
+}
+
+// Group returns a group whose routes are registered under the prefix
+func (m *Mux) Group(prefix string) *Group {
+	g := &Group{mux: m, prefix: groupPrefix(prefix)}
+	m.groups = append(m.groups, g)
+
+	return g
+}
+
+// Group returns a nested group, its prefix is appended to the prefix of
+// the group and the middleware of the group runs before its own.
+func (g *Group) Group(prefix string) *Group {
+	nested := &Group{mux: g.mux, parent: g, prefix: g.prefix + groupPrefix(prefix)}
+	g.mux.groups = append(g.mux.groups, nested)
+
+	return nested
+}
+
+// RegisterErrorHandler registers an error handler for requests served by
+// the routes of the group, or for requests under the group's prefix that no
+// route matched. The status can be ClientErrors, ServerErrors or AllErrors,
+// see Mux.RegisterErrorHandler.
+//
+// The function returns true if an existing error handler was overwritten
+func (g *Group) RegisterErrorHandler(statusCode int, handler http.HandlerFunc) bool {
+	if g.errorHandlers == nil {
+		g.errorHandlers = make(map[int]http.HandlerFunc)
+	}
+
+	_, ok := g.errorHandlers[statusCode]
+	g.errorHandlers[statusCode] = handler
+
+	return ok
+}
+
+// Use adds middleware that runs for requests served by the routes of the
+// group, after the mux middleware and before the route middleware.
+func (g *Group) Use(middleware ...Middleware) *Group {
+	g.middleware = append(g.middleware, middleware...)
+
+	return g
+}
+
+// RegisterHandler adds a Handler to the multiplexer for the route under the
+// group's prefix, see Mux.RegisterHandler.
+func (g *Group) RegisterHandler(route string, handler http.Handler) (*Route, error) {
+	return g.register(route, gowtHandler{handler: handler})
+}
+
+// RegisterRoute adds a HandlerFunc to the multiplexer for the route under
+// the group's prefix, see Mux.RegisterRoute.
+func (g *Group) RegisterRoute(route string, handler http.HandlerFunc) (*Route, error) {
+	return g.register(route, gowtHandler{handlerFunc: handler})
+}
+
+// register registers the route on the mux and adds it to the group, the
+// route is joined to the prefix with a single "/" and an empty route is the
+// prefix itself so it has no trailing "/".
+func (g *Group) register(route string, gh gowtHandler) (*Route, error) {
+	path := g.prefix
+	if route != "" {
+		path += "/" + strings.TrimPrefix(route, "/")
+	}
+	if path == "" {
+		path = "/"
+	}
+
+	r, err := g.mux.register(path, gh)
+	if err != nil {
+		return nil, err
+	}
+
+	r.group = g
+
+	return r, nil
+}
+
+// requestGroup returns the group of the route serving the request, or the
+// group with the longest prefix of the request path if no route was
+// selected.
+func (m *Mux) requestGroup(r *http.Request, route *Route) *Group {
+	if route != nil {
+		return route.group
+	}
+
+	requestPath := m.requestPath(r)
+
+	var group *Group
+	for _, g := range m.groups {
+		if group != nil && len(g.prefix) <= len(group.prefix) {
+			continue
+		}
+
+		if requestPath == g.prefix || strings.HasPrefix(requestPath, g.prefix+"/") {
+			group = g
+		}
+	}
+
+	return group
+}
+
+// groupPrefix returns the prefix with a single leading "/" and without a
+// trailing "/", the root prefix is empty.
+func groupPrefix(prefix string) string {
+	if prefix = strings.Trim(prefix, "/"); prefix == "" {
+		return ""
+	}
+
+	return "/" + prefix
+}
+
+// groupChain wraps the handler with the middleware of the group and the
+// groups it is nested in, the outermost group's middleware runs first.
+func groupChain(g *Group, h http.Handler) http.Handler {
+	for ; g != nil; g = g.parent {
+		h = chain(g.middleware, h)
+	}
+
+	return h
+}
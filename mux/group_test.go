@@ -0,0 +1,310 @@
+package mux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var groupTests = []struct {
+	description, requestURL string
+	expectedStatus          int
+	expectedOrder           string
+}{{
+	description:    "Testing: A group route is served under the group prefix.",
+	requestURL:     "/api/users",
+	expectedStatus: http.StatusOK,
+	expectedOrder:  "mux,api,route,handler",
+}, {
+	description:    "Testing: A nested group route runs the middleware of every group.",
+	requestURL:     "/api/v1/items/3",
+	expectedStatus: http.StatusOK,
+	expectedOrder:  "mux,api,v1,handler",
+}, {
+	description:    "Testing: The group prefix alone is served by the group's root route.",
+	requestURL:     "/api",
+	expectedStatus: http.StatusOK,
+	expectedOrder:  "mux,api,handler",
+}, {
+	description:    "Testing: A group route isn't served without the prefix.",
+	requestURL:     "/users",
+	expectedStatus: http.StatusNotFound,
+	expectedOrder:  "mux",
+}}
+
+func TestGroups(t *testing.T) {
+	t.Log("Testing routes registered on groups.")
+
+	for i, test := range groupTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		var order []string
+		record := func(name string) Middleware {
+			return func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					order = append(order, name)
+					next.ServeHTTP(w, r)
+				})
+			}
+		}
+		handler := func(w http.ResponseWriter, r *http.Request) {
+			order = append(order, "handler")
+		}
+
+		m := NewMux()
+		m.Use(record("mux"))
+
+		api := m.Group("/api/")
+		must(api.RegisterRoute("/users", handler)).Use(record("route"))
+		must(api.RegisterRoute("", handler))
+
+		v1 := api.Group("/v1")
+		must(v1.RegisterRoute("/items/{id:int}", handler))
+
+		api.Use(record("api"))
+		v1.Use(record("v1"))
+
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", test.requestURL, nil))
+
+		if w.Code != test.expectedStatus {
+			t.Logf("[FAIL] :: Expected the status %d but got %d.", test.expectedStatus, w.Code)
+			t.Fail()
+		}
+
+		if got := strings.Join(order, ","); got != test.expectedOrder {
+			t.Logf("[FAIL] :: Expected the order \"%s\" but got \"%s\".", test.expectedOrder, got)
+			t.Fail()
+		}
+	}
+}
+
+func TestGroupRootStrict(t *testing.T) {
+	t.Log("Testing the root route of a group is the prefix without a trailing \"/\".")
+
+	m := NewMux()
+	m.TrailingSlash(TrailingSlashStrict)
+	must(m.Group("/api").RegisterRoute("", writeBody("api")))
+
+	for _, test := range []struct {
+		requestURL     string
+		expectedStatus int
+	}{{"/api", http.StatusOK}, {"/api/", http.StatusNotFound}} {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", test.requestURL, nil))
+
+		if w.Code != test.expectedStatus {
+			t.Logf("[FAIL] :: Expected the status %d for \"%s\" but got %d.", test.expectedStatus, test.requestURL, w.Code)
+			t.Fail()
+		}
+	}
+}
+
+func TestGroupJoinsPaths(t *testing.T) {
+	t.Log("Testing group prefixes and routes are joined with a single \"/\".")
+
+	m := NewMux()
+	must(m.Group("api").RegisterRoute("users", writeBody("users")))
+	must(m.Group("/v1/").Group("items/").RegisterRoute("/{id}", writeBody("item")))
+
+	for _, test := range []struct{ requestURL, expectedBody string }{
+		{"/api/users", "users"},
+		{"/v1/items/3", "item"},
+	} {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", test.requestURL, nil))
+
+		if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != test.expectedBody {
+			t.Logf("[FAIL] :: Expected \"%s\" for %s but got %d \"%s\".", test.expectedBody, test.requestURL, w.Code, w.Body.String())
+			t.Fail()
+		}
+	}
+}
+
+var scopedErrorTests = []struct {
+	description, method, requestURL string
+	expectedStatus                  int
+	expectedBody                    string
+}{{
+	description:    "Testing: A group route uses the group's error handler.",
+	method:         "POST",
+	requestURL:     "/api/users",
+	expectedStatus: http.StatusMethodNotAllowed,
+	expectedBody:   "api 405",
+}, {
+	description:    "Testing: An unmatched path under a group prefix uses the group's error handler.",
+	method:         "GET",
+	requestURL:     "/api/missing",
+	expectedStatus: http.StatusNotFound,
+	expectedBody:   "api 404",
+}, {
+	description:    "Testing: A nested group's handler is used before its parent's.",
+	method:         "GET",
+	requestURL:     "/api/v1/items/abc",
+	expectedStatus: http.StatusNotFound,
+	expectedBody:   "v1 404",
+}, {
+	description:    "Testing: A nested group's class handler is used before the parent's exact handler.",
+	method:         "GET",
+	requestURL:     "/api/v1/limited",
+	expectedStatus: http.StatusTooManyRequests,
+	expectedBody:   "v1 4xx",
+}, {
+	description:    "Testing: A status without a group handler falls back to the mux.",
+	method:         "GET",
+	requestURL:     "/api/broken",
+	expectedStatus: http.StatusInternalServerError,
+	expectedBody:   "mux 5xx",
+}, {
+	description:    "Testing: Requests outside of the groups use the mux class handler before the default.",
+	method:         "GET",
+	requestURL:     "/missing",
+	expectedStatus: http.StatusNotFound,
+	expectedBody:   "mux 4xx",
+}, {
+	description:    "Testing: A group's catch-all is used before the mux class handler.",
+	method:         "GET",
+	requestURL:     "/legacy/moved",
+	expectedStatus: http.StatusGone,
+	expectedBody:   "legacy any",
+}, {
+	description:    "Testing: A prefix only matches whole segments.",
+	method:         "GET",
+	requestURL:     "/apiary",
+	expectedStatus: http.StatusNotFound,
+	expectedBody:   "mux 4xx",
+}}
+
+func TestScopedErrorHandlers(t *testing.T) {
+	t.Log("Testing error handlers are resolved from the most specific scope.")
+
+	for i, test := range scopedErrorTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		handler := func(body string) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+				w.Write([]byte(body))
+			}
+		}
+		status := func(code int) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				Error(w, r, code)
+			}
+		}
+
+		m := NewMux()
+		m.RegisterErrorHandler(ClientErrors, handler("mux 4xx"))
+		m.RegisterErrorHandler(ServerErrors, handler("mux 5xx"))
+
+		legacy := m.Group("/legacy")
+		legacy.RegisterErrorHandler(AllErrors, handler("legacy any"))
+		legacy.RegisterRoute("/moved", status(http.StatusGone))
+
+		api := m.Group("/api")
+		api.RegisterErrorHandler(http.StatusNotFound, handler("api 404"))
+		api.RegisterErrorHandler(http.StatusMethodNotAllowed, handler("api 405"))
+		api.RegisterErrorHandler(http.StatusTooManyRequests, handler("api 429"))
+		must(api.RegisterRoute("/users", func(w http.ResponseWriter, r *http.Request) {})).Methods("GET")
+		api.RegisterRoute("/broken", status(http.StatusInternalServerError))
+
+		v1 := api.Group("/v1")
+		v1.RegisterErrorHandler(ClientErrors, handler("v1 4xx"))
+		v1.RegisterErrorHandler(http.StatusNotFound, handler("v1 404"))
+		v1.RegisterRoute("/items/{id}", status(http.StatusNotFound))
+		v1.RegisterRoute("/limited", status(http.StatusTooManyRequests))
+
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest(test.method, test.requestURL, nil))
+
+		if w.Body.String() != test.expectedBody {
+			t.Logf("[FAIL] :: Expected the body \"%s\" but got \"%s\".", test.expectedBody, w.Body.String())
+			t.Fail()
+		}
+	}
+}
+
+var mountedErrorTests = []struct {
+	description, method, requestURL string
+	expectedBody                    string
+}{{
+	description:  "Testing: The mounted mux's own error handler is used first.",
+	method:       "POST",
+	requestURL:   "/api/users",
+	expectedBody: "sub 405",
+}, {
+	description:  "Testing: An unmatched path in the mounted mux uses the group it's mounted on.",
+	method:       "GET",
+	requestURL:   "/api/missing",
+	expectedBody: "api 404",
+}, {
+	description:  "Testing: A status without a group handler falls back to the parent mux.",
+	method:       "GET",
+	requestURL:   "/api/gone",
+	expectedBody: "parent 4xx",
+}, {
+	description:  "Testing: A status no scope handles uses the mounted mux's default.",
+	method:       "GET",
+	requestURL:   "/api/broken",
+	expectedBody: "Internal Server Error\n",
+}}
+
+func TestMountedErrorHandlers(t *testing.T) {
+	t.Log("Testing a mux mounted on another mux resolves error handlers through its scopes.")
+
+	for i, test := range mountedErrorTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		handler := func(body string) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+				w.Write([]byte(body))
+			}
+		}
+		status := func(code int) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				Error(w, r, code)
+			}
+		}
+
+		sub := NewMux()
+		sub.RegisterErrorHandler(http.StatusMethodNotAllowed, handler("sub 405"))
+		must(sub.RegisterRoute("/api/users", func(w http.ResponseWriter, r *http.Request) {})).Methods("GET")
+		sub.RegisterRoute("/api/gone", status(http.StatusGone))
+		sub.RegisterRoute("/api/broken", status(http.StatusInternalServerError))
+
+		m := NewMux()
+		m.RegisterErrorHandler(ClientErrors, handler("parent 4xx"))
+
+		api := m.Group("/api")
+		api.RegisterErrorHandler(http.StatusNotFound, handler("api 404"))
+		must(api.RegisterHandler("", sub)).Subtree()
+
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest(test.method, test.requestURL, nil))
+
+		if w.Body.String() != test.expectedBody {
+			t.Logf("[FAIL] :: Expected the body \"%s\" but got \"%s\".", test.expectedBody, w.Body.String())
+			t.Fail()
+		}
+	}
+}
+
+func TestCatchAllErrorHandler(t *testing.T) {
+	t.Log("Testing the mux catch-all is used before the default error handlers.")
+
+	m := NewMux()
+	m.RegisterErrorHandler(AllErrors, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("catch-all"))
+	})
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/missing", nil))
+
+	if w.Body.String() != "catch-all" {
+		t.Logf("[FAIL] :: Expected the catch-all but got \"%s\".", w.Body.String())
+		t.Fail()
+	}
+}
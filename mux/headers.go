@@ -0,0 +1,28 @@
+package mux
+
+import "net/http"
+
+// DefaultHeaders sets response headers that are added to every response
+// from the mux, including redirects and error responses. The headers are
+// set before the handler runs so a handler overrides a default by setting
+// the header itself, or removes it with w.Header().Del.
+func (m *Mux) DefaultHeaders(h http.Header) {
+	m.defaultHeaders = h.Clone()
+}
+
+// DefaultHeaders sets response headers that are added to every response
+// from the route. They replace the mux's default headers with the same
+// name and, like those, can be overridden by the handler.
+func (r *Route) DefaultHeaders(h http.Header) *Route {
+	r.defaultHeaders = h.Clone()
+
+	return r
+}
+
+// setHeaders copies the headers to the response, replacing any values
+// the response already has for those headers.
+func setHeaders(w http.ResponseWriter, h http.Header) {
+	for k, v := range h {
+		w.Header()[k] = append([]string(nil), v...)
+	}
+}
@@ -0,0 +1,166 @@
+package mux
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"sync"
+)
+
+// SlogLogger adapts a *slog.Logger to both Logger and StructuredLogger
+type SlogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger returns a logger that writes to the slog.Logger, the
+// default slog logger is used if l is nil.
+func NewSlogLogger(l *slog.Logger) *SlogLogger {
+	if l == nil {
+		l = slog.Default()
+	}
+
+	return &SlogLogger{logger: l}
+}
+
+// Log writes the message and fields at the level provided
+func (l *SlogLogger) Log(level Level, msg string, keyvals ...interface{}) {
+	l.logger.Log(context.Background(), slog.Level(level), msg, keyvals...)
+}
+
+// Info writes a formatted message at the info level
+func (l *SlogLogger) Info(format string, data ...interface{}) {
+	l.Log(LevelInfo, fmt.Sprintf(format, data...))
+}
+
+// Warn writes a formatted message at the warn level
+func (l *SlogLogger) Warn(format string, data ...interface{}) {
+	l.Log(LevelWarn, fmt.Sprintf(format, data...))
+}
+
+// Debug writes a formatted message at the debug level
+func (l *SlogLogger) Debug(format string, data ...interface{}) {
+	l.Log(LevelDebug, fmt.Sprintf(format, data...))
+}
+
+// Error writes a formatted message at the error level
+func (l *SlogLogger) Error(format string, data ...interface{}) {
+	l.Log(LevelError, fmt.Sprintf(format, data...))
+}
+
+// StdLogger adapts a *log.Logger from the standard library to Logger,
+// every line is prefixed with its level.
+type StdLogger struct {
+	logger *log.Logger
+}
+
+// NewStdLogger returns a logger that writes to the log.Logger, the
+// standard logger is used if l is nil.
+func NewStdLogger(l *log.Logger) *StdLogger {
+	if l == nil {
+		l = log.Default()
+	}
+
+	return &StdLogger{logger: l}
+}
+
+// Info writes a formatted message at the info level
+func (l *StdLogger) Info(format string, data ...interface{}) {
+	l.logger.Printf("[INFO] "+format, data...)
+}
+
+// Warn writes a formatted message at the warn level
+func (l *StdLogger) Warn(format string, data ...interface{}) {
+	l.logger.Printf("[WARN] "+format, data...)
+}
+
+// Debug writes a formatted message at the debug level
+func (l *StdLogger) Debug(format string, data ...interface{}) {
+	l.logger.Printf("[DEBUG] "+format, data...)
+}
+
+// Error writes a formatted message at the error level
+func (l *StdLogger) Error(format string, data ...interface{}) {
+	l.logger.Printf("[ERROR] "+format, data...)
+}
+
+// LogEntry is a log entry stored by TestLogger
+type LogEntry struct {
+	Level   Level
+	Message string
+	Fields  map[string]interface{}
+}
+
+// TestLogger keeps log entries in memory so tests can check what the mux
+// logged. It implements both Logger and StructuredLogger and is safe for
+// concurrent use.
+type TestLogger struct {
+	mu      sync.Mutex
+	entries []LogEntry
+}
+
+// NewTestLogger returns an empty TestLogger
+func NewTestLogger() *TestLogger {
+	return &TestLogger{}
+}
+
+// Log stores the message and fields at the level provided
+func (l *TestLogger) Log(level Level, msg string, keyvals ...interface{}) {
+	fields := make(map[string]interface{}, len(keyvals)/2)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fields[fmt.Sprint(keyvals[i])] = keyvals[i+1]
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = append(l.entries, LogEntry{Level: level, Message: msg, Fields: fields})
+}
+
+// Info stores a formatted message at the info level
+func (l *TestLogger) Info(format string, data ...interface{}) {
+	l.Log(LevelInfo, fmt.Sprintf(format, data...))
+}
+
+// Warn stores a formatted message at the warn level
+func (l *TestLogger) Warn(format string, data ...interface{}) {
+	l.Log(LevelWarn, fmt.Sprintf(format, data...))
+}
+
+// Debug stores a formatted message at the debug level
+func (l *TestLogger) Debug(format string, data ...interface{}) {
+	l.Log(LevelDebug, fmt.Sprintf(format, data...))
+}
+
+// Error stores a formatted message at the error level
+func (l *TestLogger) Error(format string, data ...interface{}) {
+	l.Log(LevelError, fmt.Sprintf(format, data...))
+}
+
+// Entries returns a copy of the entries logged so far
+func (l *TestLogger) Entries() []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]LogEntry(nil), l.entries...)
+}
+
+// EntriesAt returns the entries logged at the level provided
+func (l *TestLogger) EntriesAt(level Level) []LogEntry {
+	var entries []LogEntry
+	for _, e := range l.Entries() {
+		if e.Level == level {
+			entries = append(entries, e)
+		}
+	}
+
+	return entries
+}
+
+// Reset removes every entry
+func (l *TestLogger) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.entries = nil
+}
@@ -0,0 +1,236 @@
+package mux
+
+import (
+	"net/http"
+	"strings"
+)
+
+// matcher is an extra condition a request must satisfy, on top of the
+// path and the allowed methods, for a route to be selected.
+type matcher func(r *http.Request) bool
+
+// Methods restricts the route to the HTTP methods provided. A request using
+// any other method on a matching path is answered with 405 and an Allow
+// header listing the methods that are accepted. Routes that allow GET also
+// accept HEAD.
+func (r *Route) Methods(methods ...string) *Route {
+	for _, method := range methods {
+		r.allowedMethods = append(r.allowedMethods, strings.ToUpper(method))
+	}
+
+	return r
+}
+
+// Headers adds a condition that the request carries the headers provided.
+// Pairs are given as key, value; an empty value only requires the header
+// to be present. A value matches the whole header or any of its comma
+// separated elements so lists like Accept can be matched.
+func (r *Route) Headers(pairs ...string) *Route {
+	for i := 0; i < len(pairs); i += 2 {
+		key, value := pairs[i], ""
+		if i+1 < len(pairs) {
+			value = pairs[i+1]
+		}
+
+		r.matchers = append(r.matchers, headerMatcher(key, value))
+	}
+
+	return r
+}
+
+// Queries adds a condition that the request carries the query parameters
+// provided. Pairs are given as key, value; an empty value only requires the
+// parameter to be present. A value written as a variable, like "{fmt}" or
+// "{page: int}", accepts any value of that type and makes it available
+// through GetVariables and GetVariableByName.
+func (r *Route) Queries(pairs ...string) *Route {
+	for i := 0; i < len(pairs); i += 2 {
+		key, value := pairs[i], ""
+		if i+1 < len(pairs) {
+			value = pairs[i+1]
+		}
+
+		if isVariable(value) {
+			info, err := getVariableInfo(value)
+			if err == nil {
+				info.route = r.url
+				info.query = key
+				r.variables = append(r.variables, info)
+				r.hasVariables = true
+				r.matchers = append(r.matchers, queryVariableMatcher(info))
+				continue
+			}
+		}
+
+		r.matchers = append(r.matchers, queryMatcher(key, value))
+	}
+
+	return r
+}
+
+// Schemes adds a condition that the request was made using one of the
+// schemes provided, such as "https".
+func (r *Route) Schemes(schemes ...string) *Route {
+	allowed := make([]string, len(schemes))
+	for i, scheme := range schemes {
+		allowed[i] = strings.ToLower(scheme)
+	}
+
+	r.matchers = append(r.matchers, func(req *http.Request) bool {
+		scheme := requestScheme(req)
+		for _, s := range allowed {
+			if s == scheme {
+				return true
+			}
+		}
+
+		return false
+	})
+
+	return r
+}
+
+// MatcherFunc adds a custom condition to the route, the route is only
+// selected when the function returns true for the request.
+func (r *Route) MatcherFunc(f func(*http.Request) bool) *Route {
+	r.matchers = append(r.matchers, f)
+
+	return r
+}
+
+// hasConditions reports if the route is restricted by anything other than
+// its path. Routes with conditions are not replaced when the same path is
+// registered again so that several routes can share a path.
+func (r *Route) hasConditions() bool {
+	return len(r.allowedMethods) > 0 || len(r.matchers) > 0 || len(r.produces) > 0
+}
+
+// matchConditions checks the request against all of the conditions
+// added to the route.
+func (r *Route) matchConditions(req *http.Request) bool {
+	for _, m := range r.matchers {
+		if !m(req) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// allowsMethod checks the request method against the methods the route
+// was restricted to, a route without restrictions allows every method.
+func (r *Route) allowsMethod(method string) bool {
+	if len(r.allowedMethods) == 0 {
+		return true
+	}
+
+	for _, m := range r.allowedMethods {
+		if m == method || (m == http.MethodGet && method == http.MethodHead) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// allowMethods returns the methods the route accepts for the Allow header,
+// HEAD is listed whenever GET is since it's answered too.
+func (r *Route) allowMethods() []string {
+	if containsString(r.allowedMethods, http.MethodHead) {
+		return r.allowedMethods
+	}
+
+	methods := make([]string, 0, len(r.allowedMethods)+1)
+	for _, m := range r.allowedMethods {
+		methods = append(methods, m)
+		if m == http.MethodGet {
+			methods = append(methods, http.MethodHead)
+		}
+	}
+
+	return methods
+}
+
+// headerMatcher matches a header against a value, see Headers.
+func headerMatcher(key, value string) matcher {
+	return func(r *http.Request) bool {
+		values := r.Header.Values(key)
+		if len(values) == 0 {
+			return false
+		}
+
+		if value == "" {
+			return true
+		}
+
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+
+			for _, element := range strings.Split(v, ",") {
+				if strings.TrimSpace(element) == value {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+}
+
+// queryMatcher matches a query parameter against a literal value.
+func queryMatcher(key, value string) matcher {
+	return func(r *http.Request) bool {
+		values, ok := r.URL.Query()[key]
+		if !ok {
+			return false
+		}
+
+		if value == "" {
+			return true
+		}
+
+		for _, v := range values {
+			if v == value {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// queryVariableMatcher matches a query parameter that holds a variable,
+// the parameter has to be convertible to the variable type and present
+// unless the variable is optional.
+func queryVariableMatcher(info variableInfo) matcher {
+	return func(r *http.Request) bool {
+		values, ok := r.URL.Query()[info.query]
+		if !ok || len(values) == 0 {
+			return info.optional
+		}
+
+		_, err := cast(info.kind, values[0])
+		return err == nil
+	}
+}
+
+// requestScheme returns the scheme the request was made with
+func requestScheme(r *http.Request) string {
+	if r.URL.Scheme != "" {
+		return strings.ToLower(r.URL.Scheme)
+	}
+
+	if r.TLS != nil {
+		return "https"
+	}
+
+	return "http"
+}
+
+// isVariable reports if the string is a single variable declaration
+// like "{name}"
+func isVariable(s string) bool {
+	return len(s) > 1 && s[0] == '{' && strings.Index(s, "}") == len(s)-1
+}
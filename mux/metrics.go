@@ -0,0 +1,331 @@
+package mux
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDurationBuckets are the upper bounds, in seconds, of the request
+// duration histogram buckets.
+var DefaultDurationBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultSizeBuckets are the upper bounds, in bytes, of the response size
+// histogram buckets.
+var DefaultSizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+
+// unmatchedRoute is the route label of requests that didn't match a route
+const unmatchedRoute = "unmatched"
+
+// MetricsConfig configures the metrics collected by Metrics
+type MetricsConfig struct {
+	// Namespace is prefixed to every metric name, "myapp" turns
+	// http_requests_total into myapp_http_requests_total.
+	Namespace string
+	// DurationBuckets defaults to DefaultDurationBuckets
+	DurationBuckets []float64
+	// SizeBuckets defaults to DefaultSizeBuckets
+	SizeBuckets []float64
+}
+
+// Metrics collects request metrics labeled by route template, method and
+// status class and serves them in the Prometheus text exposition format.
+// Labeling by the route template instead of the path keeps the number of
+// series bounded.
+//
+// The collected metrics are:
+//   - http_requests_total, a counter of served requests
+//   - http_request_duration_seconds, a histogram of request durations
+//   - http_response_size_bytes, a histogram of response body sizes
+//   - http_requests_in_flight, a gauge of requests being served by a route,
+//     a handler that exceeds its time limit is counted until it returns
+//
+// The in flight, queued and shed requests of concurrency limiters added
+// with AddLimiter are reported labeled by the limiter name.
+type Metrics struct {
+	mu sync.Mutex
+
+	prefix          string
+	durationBuckets []float64
+	sizeBuckets     []float64
+
+	requests  map[seriesKey]uint64
+	durations map[seriesKey]*histogram
+	sizes     map[seriesKey]*histogram
+	inFlight  map[seriesKey]int64
+	limiters  []*ConcurrencyLimiter
+}
+
+// seriesKey holds the label values of a series, status is empty for the
+// in flight gauge.
+type seriesKey struct {
+	route, method, status string
+}
+
+// histogram holds the count of observations for each bucket, the last
+// count is the +Inf bucket.
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewMetrics returns an empty Metrics, register it on the mux with
+// m.Metrics and expose it by registering it as a handler.
+func NewMetrics(config MetricsConfig) *Metrics {
+	if config.DurationBuckets == nil {
+		config.DurationBuckets = DefaultDurationBuckets
+	}
+
+	if config.SizeBuckets == nil {
+		config.SizeBuckets = DefaultSizeBuckets
+	}
+
+	prefix := ""
+	if config.Namespace != "" {
+		prefix = config.Namespace + "_"
+	}
+
+	return &Metrics{
+		prefix:          prefix,
+		durationBuckets: sortedBuckets(config.DurationBuckets),
+		sizeBuckets:     sortedBuckets(config.SizeBuckets),
+		requests:        make(map[seriesKey]uint64),
+		durations:       make(map[seriesKey]*histogram),
+		sizes:           make(map[seriesKey]*histogram),
+		inFlight:        make(map[seriesKey]int64),
+	}
+}
+
+// Metrics sets the metrics that every request served by the mux is
+// recorded in.
+func (m *Mux) Metrics(metrics *Metrics) {
+	m.metrics = metrics
+}
+
+// AddLimiter reports the state of the concurrency limiter with the metrics
+func (mt *Metrics) AddLimiter(l *ConcurrencyLimiter) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	mt.limiters = append(mt.limiters, l)
+}
+
+// begin increments the in flight gauge of the route and returns the
+// function that decrements it.
+func (mt *Metrics) begin(route *Route, method string) func() {
+	key := seriesKey{route: routeLabel(route), method: methodLabel(method)}
+
+	mt.mu.Lock()
+	mt.inFlight[key]++
+	mt.mu.Unlock()
+
+	return func() {
+		mt.mu.Lock()
+		mt.inFlight[key]--
+		mt.mu.Unlock()
+	}
+}
+
+// observe records a served request
+func (mt *Metrics) observe(route *Route, method string, status int, size int64, duration time.Duration) {
+	key := seriesKey{route: routeLabel(route), method: methodLabel(method), status: statusClass(status)}
+
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	mt.requests[key]++
+
+	if mt.durations[key] == nil {
+		mt.durations[key] = newHistogram(mt.durationBuckets)
+		mt.sizes[key] = newHistogram(mt.sizeBuckets)
+	}
+
+	mt.durations[key].observe(mt.durationBuckets, duration.Seconds())
+	mt.sizes[key].observe(mt.sizeBuckets, float64(size))
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format
+func (mt *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	mt.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format
+func (mt *Metrics) WriteTo(w io.Writer) (int64, error) {
+	mt.mu.Lock()
+	defer mt.mu.Unlock()
+
+	var b strings.Builder
+
+	name := mt.prefix + "http_requests_total"
+	fmt.Fprintf(&b, "# HELP %s Total number of HTTP requests served.\n# TYPE %s counter\n", name, name)
+	keys := make([]seriesKey, 0, len(mt.requests))
+	for key := range mt.requests {
+		keys = append(keys, key)
+	}
+
+	for _, key := range sortKeys(keys) {
+		fmt.Fprintf(&b, "%s%s %d\n", name, key.labels(), mt.requests[key])
+	}
+
+	writeHistograms(&b, mt.prefix+"http_request_duration_seconds", "Duration of HTTP requests in seconds.", mt.durationBuckets, mt.durations)
+	writeHistograms(&b, mt.prefix+"http_response_size_bytes", "Size of HTTP response bodies in bytes.", mt.sizeBuckets, mt.sizes)
+
+	name = mt.prefix + "http_requests_in_flight"
+	fmt.Fprintf(&b, "# HELP %s Number of HTTP requests being served.\n# TYPE %s gauge\n", name, name)
+	keys = keys[:0]
+	for key := range mt.inFlight {
+		keys = append(keys, key)
+	}
+
+	for _, key := range sortKeys(keys) {
+		fmt.Fprintf(&b, "%s%s %d\n", name, key.labels(), mt.inFlight[key])
+	}
+
+	if len(mt.limiters) > 0 {
+		writeLimiters(&b, mt.prefix, mt.limiters)
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// writeHistograms writes every series of a histogram metric
+func writeHistograms(b *strings.Builder, name, help string, buckets []float64, series map[seriesKey]*histogram) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+
+	keys := make([]seriesKey, 0, len(series))
+	for key := range series {
+		keys = append(keys, key)
+	}
+
+	for _, key := range sortKeys(keys) {
+		h := series[key]
+		labels := key.labels()
+		labels = labels[:len(labels)-1]
+
+		var cumulative uint64
+		for i, bound := range buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(b, "%s_bucket%s,le=\"%s\"} %d\n", name, labels, formatFloat(bound), cumulative)
+		}
+
+		fmt.Fprintf(b, "%s_bucket%s,le=\"+Inf\"} %d\n", name, labels, h.count)
+		fmt.Fprintf(b, "%s_sum%s} %s\n", name, labels, formatFloat(h.sum))
+		fmt.Fprintf(b, "%s_count%s} %d\n", name, labels, h.count)
+	}
+}
+
+// writeLimiters writes the state of the concurrency limiters
+func writeLimiters(b *strings.Builder, prefix string, limiters []*ConcurrencyLimiter) {
+	stats := make([]ConcurrencyStats, len(limiters))
+	for i, l := range limiters {
+		stats[i] = l.Stats()
+	}
+
+	name := prefix + "http_concurrency_in_flight"
+	fmt.Fprintf(b, "# HELP %s Number of requests being served by the concurrency limiter.\n# TYPE %s gauge\n", name, name)
+	for i, l := range limiters {
+		fmt.Fprintf(b, "%s{limiter=\"%s\"} %d\n", name, escapeLabel(l.name), stats[i].InFlight)
+	}
+
+	name = prefix + "http_concurrency_queue_depth"
+	fmt.Fprintf(b, "# HELP %s Number of requests waiting in the concurrency limiter queue.\n# TYPE %s gauge\n", name, name)
+	for i, l := range limiters {
+		fmt.Fprintf(b, "%s{limiter=\"%s\"} %d\n", name, escapeLabel(l.name), stats[i].Queued)
+	}
+
+	name = prefix + "http_concurrency_shed_total"
+	fmt.Fprintf(b, "# HELP %s Total number of requests shed by the concurrency limiter.\n# TYPE %s counter\n", name, name)
+	for i, l := range limiters {
+		fmt.Fprintf(b, "%s{limiter=\"%s\"} %d\n", name, escapeLabel(l.name), stats[i].Shed)
+	}
+}
+
+// newHistogram returns an empty histogram for the buckets
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{counts: make([]uint64, len(buckets)+1)}
+}
+
+// observe adds the value to the first bucket it fits in
+func (h *histogram) observe(buckets []float64, value float64) {
+	i := sort.SearchFloat64s(buckets, value)
+	h.counts[i]++
+	h.sum += value
+	h.count++
+}
+
+// labels returns the label set of the series
+func (k seriesKey) labels() string {
+	labels := fmt.Sprintf("{route=\"%s\",method=\"%s\"", escapeLabel(k.route), escapeLabel(k.method))
+	if k.status != "" {
+		labels += fmt.Sprintf(",status=\"%s\"", k.status)
+	}
+
+	return labels + "}"
+}
+
+// sortKeys sorts the series keys so the output is stable
+func sortKeys(keys []seriesKey) []seriesKey {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].route != keys[j].route {
+			return keys[i].route < keys[j].route
+		}
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].status < keys[j].status
+	})
+
+	return keys
+}
+
+// sortedBuckets returns a sorted copy of the bucket bounds
+func sortedBuckets(buckets []float64) []float64 {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	return sorted
+}
+
+// routeLabel returns the template of the route, or unmatchedRoute
+func routeLabel(route *Route) string {
+	if route == nil {
+		return unmatchedRoute
+	}
+
+	return route.template()
+}
+
+// methodLabel returns the method, methods that aren't defined by HTTP are
+// reported as OTHER so clients can't create new series.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+
+	return "OTHER"
+}
+
+// statusClass returns the class of the status code, e.g. 2xx
+func statusClass(status int) string {
+	return strconv.Itoa(status/100) + "xx"
+}
+
+// escapeLabel escapes a label value for the text exposition format
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// formatFloat formats a float for the text exposition format
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
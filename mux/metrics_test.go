@@ -0,0 +1,131 @@
+package mux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var metricsTests = []struct {
+	description, method, requestURL string
+	expectedLines                   []string
+}{{
+	description: "Testing: Requests are counted by route template, method and status class.",
+	method:      "GET",
+	requestURL:  "/users/42",
+	expectedLines: []string{
+		`http_requests_total{route="/users/{id}",method="GET",status="2xx"} 1`,
+		`http_request_duration_seconds_count{route="/users/{id}",method="GET",status="2xx"} 1`,
+		`http_response_size_bytes_bucket{route="/users/{id}",method="GET",status="2xx",le="100"} 1`,
+		`http_response_size_bytes_sum{route="/users/{id}",method="GET",status="2xx"} 5`,
+		`http_requests_in_flight{route="/users/{id}",method="GET"} 0`,
+	},
+}, {
+	description: "Testing: Unmatched requests share a single route label.",
+	method:      "GET",
+	requestURL:  "/missing/path",
+	expectedLines: []string{
+		`http_requests_total{route="unmatched",method="GET",status="4xx"} 1`,
+	},
+}, {
+	description: "Testing: Unknown methods are reported as OTHER.",
+	method:      "BREW",
+	requestURL:  "/users/42",
+	expectedLines: []string{
+		`http_requests_total{route="/users/{id}",method="OTHER",status="2xx"} 1`,
+	},
+}}
+
+func TestMetrics(t *testing.T) {
+	t.Log("Testing request metrics are recorded and exposed.")
+
+	for i, test := range metricsTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		metrics := NewMetrics(MetricsConfig{})
+		m := NewMux()
+		m.Metrics(metrics)
+		m.RegisterRoute("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("hello"))
+		})
+		m.RegisterHandler("/metrics", metrics)
+
+		m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(test.method, test.requestURL, nil))
+
+		w := httptest.NewRecorder()
+		metrics.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+		body := w.Body.String()
+		for _, line := range test.expectedLines {
+			if !strings.Contains(body, line+"\n") {
+				t.Logf("[FAIL] :: Expected the line \"%s\" in:\n%s", line, body)
+				t.Fail()
+			}
+		}
+	}
+}
+
+func TestMetricsInFlight(t *testing.T) {
+	t.Log("Testing the in flight gauge counts requests being served.")
+
+	metrics := NewMetrics(MetricsConfig{Namespace: "app"})
+	m := NewMux()
+	m.Metrics(metrics)
+
+	var body string
+	m.RegisterRoute("/slow", func(w http.ResponseWriter, r *http.Request) {
+		recorder := httptest.NewRecorder()
+		metrics.ServeHTTP(recorder, r)
+		body = recorder.Body.String()
+	})
+
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/slow", nil))
+
+	expected := `app_http_requests_in_flight{route="/slow",method="GET"} 1`
+	if !strings.Contains(body, expected) {
+		t.Logf("[FAIL] :: Expected the line \"%s\" in:\n%s", expected, body)
+		t.Fail()
+	}
+}
+
+func TestMetricsInFlightAfterTimeout(t *testing.T) {
+	t.Log("Testing a handler that exceeds its time limit is counted in flight until it returns.")
+
+	metrics := NewMetrics(MetricsConfig{})
+	m := NewMux()
+	m.Metrics(metrics)
+
+	release := make(chan struct{})
+	must(m.RegisterRoute("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})).Timeout(10 * time.Millisecond)
+
+	inFlight := func(count string) bool {
+		recorder := httptest.NewRecorder()
+		metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+		return strings.Contains(recorder.Body.String(), `http_requests_in_flight{route="/slow",method="GET"} `+count+"\n")
+	}
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/slow", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Logf("[FAIL] :: Expected status code %d but got status code %d.", http.StatusServiceUnavailable, w.Code)
+		t.Fail()
+	}
+
+	if !inFlight("1") {
+		t.Log("[FAIL] :: Expected the timed out request to be in flight while its handler runs.")
+		t.Fail()
+	}
+
+	close(release)
+	waitFor(func() bool { return inFlight("0") })
+
+	if !inFlight("0") {
+		t.Log("[FAIL] :: Expected the request to leave the in flight gauge once its handler returned.")
+		t.Fail()
+	}
+}
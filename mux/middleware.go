@@ -0,0 +1,32 @@
+package mux
+
+import "net/http"
+
+// Middleware wraps a handler with behaviour that runs before and/or after
+// the handler, such as RequestID.
+type Middleware func(http.Handler) http.Handler
+
+// Use adds middleware that runs for every request served by the mux,
+// including requests that end in an error handler or a redirect. Mux
+// middleware runs before the route is selected, in the order it was added.
+func (m *Mux) Use(middleware ...Middleware) {
+	m.middleware = append(m.middleware, middleware...)
+}
+
+// Use adds middleware that only runs for requests served by the route,
+// after the mux middleware and in the order it was added.
+func (r *Route) Use(middleware ...Middleware) *Route {
+	r.middleware = append(r.middleware, middleware...)
+
+	return r
+}
+
+// chain wraps the handler with the middleware so that the first
+// middleware is the outermost.
+func chain(middleware []Middleware, h http.Handler) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+
+	return h
+}
@@ -4,47 +4,126 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 )
 
 // Mux - A multiplexer object that is used for registering routes
 //
-// routes []Route - The array of routes that have been registered to the multiplexer
+// routes []*Route - The array of routes that have been registered to the multiplexer
+// groups []*Group - The groups created on the multiplexer
 // errorHandlers map[int]Route - A map of routes to HTTP status codes
-// logger - A logger interface that can be set by a consumer so that
-// the mux can log actions to the users logging system
+// defaultErrorHandlers - The status codes that still have the default handler
+// logger, structured, logLevel - The loggers that can be set by a consumer so
+// that the mux can log actions to the users logging system
+// middleware - Middleware that runs for every request
+// tracer - The tracer that creates a span for every request
+// metrics - The metrics every request is recorded in
+// timeout - The default time limit for requests served by a route
+// encoders, encoderTypes - The encoders Render uses and the order of preference
+// of their media types
+// errorMapper - Maps the errors returned by error routes to status codes
+// problems - Makes the default error handlers write problem details when set
+// slashPolicy, casePolicy, cleanPath, redirectCode, encodedPath - How request
+// paths are matched to the routes and redirected to their canonical form
+// versioning - How the API version of a request is resolved
+// cors - The CORS configuration for routes that don't set their own
+// defaultHeaders - Response headers added to every response
+// accessLog - The format of the access log written for every request
 type Mux struct {
-	routes        []Route
-	errorHandlers map[int]http.HandlerFunc
+	routes               []*Route
+	groups               []*Group
+	errorHandlers        map[int]http.HandlerFunc
+	defaultErrorHandlers map[int]bool
 
-	logger
+	slashPolicy  TrailingSlashPolicy
+	casePolicy   CasePolicy
+	cleanPath    bool
+	redirectCode int
+	encodedPath  bool
+
+	versioning Versioning
+	cors       *CORS
+
+	defaultHeaders http.Header
+	accessLog      AccessLogFormat
+
+	logger     Logger
+	structured StructuredLogger
+	logLevel   Level
+
+	middleware []Middleware
+	tracer     Tracer
+	metrics    *Metrics
+	timeout    time.Duration
+
+	encoders     map[string]Encoder
+	encoderTypes []string
+
+	errorMapper ErrorMapper
+	problems    *ProblemConfig
 }
 
-// NewMux returns a new Mux object with the default not found handler registered,
-// this returns 404 a handler wasn't found for the route received.
+// NewMux returns a new Mux object with the default error handlers registered,
+// these return 403 if CORS doesn't allow the request origin, 404 if a handler
+// wasn't found for the route received, 405 if the route doesn't accept the
+// request method, 406, 413, 415 and 429 when a route's restrictions aren't
+// met and 500 for errors returned by error routes. The JSON, XML and plain
+// text encoders used by Render are registered as well.
 func NewMux() *Mux {
-	errorHandlers := make(map[int]http.HandlerFunc, 1)
+	errorHandlers := make(map[int]http.HandlerFunc, 8)
+	errorHandlers[http.StatusForbidden] = DefaultForbiddenHandler
 	errorHandlers[http.StatusNotFound] = DefaultNotFoundHandler
+	errorHandlers[http.StatusMethodNotAllowed] = DefaultMethodNotAllowedHandler
+	errorHandlers[http.StatusNotAcceptable] = DefaultNotAcceptableHandler
+	errorHandlers[http.StatusRequestEntityTooLarge] = DefaultRequestEntityTooLargeHandler
+	errorHandlers[http.StatusUnsupportedMediaType] = DefaultUnsupportedMediaTypeHandler
+	errorHandlers[http.StatusTooManyRequests] = DefaultTooManyRequestsHandler
+	errorHandlers[http.StatusInternalServerError] = DefaultInternalServerErrorHandler
+
+	defaultErrorHandlers := make(map[int]bool, len(errorHandlers))
+	for status := range errorHandlers {
+		defaultErrorHandlers[status] = true
+	}
 
-	return &Mux{
-		errorHandlers: errorHandlers,
+	m := &Mux{
+		errorHandlers:        errorHandlers,
+		defaultErrorHandlers: defaultErrorHandlers,
 	}
+	m.encoders, m.encoderTypes = defaultEncoders()
+
+	return m
 }
 
 // RegisterLogger registers a logger for the multiplexer that can log actions
-// to the provided logging system. logger is a simple interface that provides
-// a (hopefully) commonplace log functionality.
+// to the provided logging system. Logger is a simple interface that provides
+// a (hopefully) commonplace log functionality. If the logger also implements
+// StructuredLogger the mux logs fields through it.
 //
 // RegisterLogger will make an attempt to write an info level log entry to
 // verify that the logger is working.
-func (m *Mux) RegisterLogger(l logger) {
+func (m *Mux) RegisterLogger(l Logger) {
 	m.logger = l
+	m.structured, _ = l.(StructuredLogger)
 
-	m.log(infoLevel, "Logger has been registered for GOWT Mux.")
+	m.log(LevelInfo, "Logger has been registered for GOWT Mux.")
+}
+
+// RegisterStructuredLogger registers a structured logger for the multiplexer,
+// the mux logs messages along with their fields through it.
+//
+// RegisterStructuredLogger will make an attempt to write an info level log
+// entry to verify that the logger is working.
+func (m *Mux) RegisterStructuredLogger(l StructuredLogger) {
+	m.logger = nil
+	m.structured = l
+
+	m.log(LevelInfo, "Logger has been registered for GOWT Mux.")
 }
 
 // RegisterHandler adds a Handler to the multiplexer for the route specified. If the
 // route that is being used has already been added, the existing route will be
-// replaced.
+// replaced unless it was given conditions such as Methods or Headers.
 // If the route was of type HandlerFunc, the HandlerFunc will be replaced with a
 // Handler.
 func (m *Mux) RegisterHandler(route string, handler http.Handler) (*Route, error) {
@@ -55,7 +134,7 @@ func (m *Mux) RegisterHandler(route string, handler http.Handler) (*Route, error
 
 // RegisterRoute adds a HandlerFunc to the multiplexer for the route specified. If
 // the route that is being used has already been added, the existing route will be
-// replaced.
+// replaced unless it was given conditions such as Methods or Headers.
 // If the route was of type Handler, the Handler will be replaced with a HandlerFunc.
 func (m *Mux) RegisterRoute(route string, handler http.HandlerFunc) (*Route, error) {
 	gh := gowtHandler{handlerFunc: handler}
@@ -64,7 +143,16 @@ func (m *Mux) RegisterRoute(route string, handler http.HandlerFunc) (*Route, err
 }
 
 // RegisterErrorHandler registers an http.HandlerFunc for a status code providing
-// a central place for error handlers to live.
+// a central place for error handlers to live. ClientErrors and ServerErrors
+// register a handler for every 4xx or 5xx status without its own handler and
+// AllErrors registers a catch-all.
+//
+// Handlers are looked up from the most specific scope outward: the groups of
+// the request from the innermost, then the mux, then the scopes of the route
+// a mux is mounted on when it's registered as a handler on another mux.
+// Within a scope the handler for the status is used before the class handler
+// and the catch-all. The default handlers registered by NewMux are only used
+// if no other handler applies.
 //
 // The function returns true if an existing error handler was updated/overwritten
 func (m *Mux) RegisterErrorHandler(statusCode int, handler http.HandlerFunc) bool {
@@ -72,6 +160,7 @@ func (m *Mux) RegisterErrorHandler(statusCode int, handler http.HandlerFunc) boo
 	// if ok is true, the map contained a value
 	_, ok := m.errorHandlers[statusCode]
 	m.errorHandlers[statusCode] = handler
+	delete(m.defaultErrorHandlers, statusCode)
 
 	return ok
 }
@@ -79,12 +168,7 @@ func (m *Mux) RegisterErrorHandler(statusCode int, handler http.HandlerFunc) boo
 // GetVariables returns a slice of interface{} that contains all the variables for
 // request.
 func (m *Mux) GetVariables(request *http.Request) (variables []interface{}, err error) {
-	var infoList []variableInfo
-	for _, route := range m.routes {
-		if matchRoute(route, request.URL.Path) {
-			infoList = append(infoList, route.variables...)
-		}
-	}
+	infoList := m.requestVariables(request)
 
 	if len(infoList) == 0 {
 		err = errors.New("No variables matched for the route and request")
@@ -92,7 +176,7 @@ func (m *Mux) GetVariables(request *http.Request) (variables []interface{}, err
 	}
 
 	for _, v := range infoList {
-		val, e := getVariableFromRequest(v, request.URL.Path)
+		val, e := m.getVariableFromRequest(v, request)
 
 		if e != nil {
 			variables = nil
@@ -108,12 +192,7 @@ func (m *Mux) GetVariables(request *http.Request) (variables []interface{}, err
 
 // GetVariableByName returns an interface{} that contains the value for the request
 func (m *Mux) GetVariableByName(name string, request *http.Request) (variable interface{}, err error) {
-	var infoList []variableInfo
-	for _, route := range m.routes {
-		if matchRoute(route, request.URL.Path) {
-			infoList = append(infoList, route.variables...)
-		}
-	}
+	infoList := m.requestVariables(request)
 
 	if len(infoList) == 0 {
 		err = fmt.Errorf("No variables found for url \"%s\"", request.URL.Path)
@@ -122,7 +201,7 @@ func (m *Mux) GetVariableByName(name string, request *http.Request) (variable in
 
 	for _, v := range infoList {
 		if v.name == name {
-			variable, err = getVariableFromRequest(v, request.URL.Path)
+			variable, err = m.getVariableFromRequest(v, request)
 		}
 	}
 
@@ -131,17 +210,101 @@ func (m *Mux) GetVariableByName(name string, request *http.Request) (variable in
 
 // ServeHTTP matches the route incoming to the routes registered and calls the
 // matched handler. If the route contains a variable, the match is based around
-// the variable value
+// the variable value.
+//
+// Requests are redirected to the canonical form of their path when path
+// cleaning is enabled or the trailing slash policy asks for it.
+//
+// When no route matches the request the registered error handler is called,
+// 405 if a route only failed on the method restriction and 404 otherwise.
+//
+// Routes with handlers registered per version are served by the handler for
+// the version the request resolves to, see Versioning.
+//
+// CORS preflight requests are answered by the mux for routes with a CORS
+// configuration, see CORS.
 func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	url := r.URL.Path
+	start := time.Now()
+	sw := newStatusWriter(w)
+	state := &requestState{mux: m, parent: stateFromRequest(r)}
+
+	r, span := m.startSpan(r)
+
+	chain(m.middleware, http.HandlerFunc(m.serve)).ServeHTTP(sw, withState(r, state))
+
+	endSpan(span, r, state.route, sw.Status())
+
+	if m.metrics != nil {
+		m.metrics.observe(state.route, r.Method, sw.Status(), sw.bytes, time.Since(start))
+	}
+
+	m.logAccess(r, state, sw, start)
+}
+
+// serve does the actual work of ServeHTTP, the route that is selected is
+// recorded in the request state.
+func (m *Mux) serve(w http.ResponseWriter, r *http.Request) {
+	setHeaders(w, m.defaultHeaders)
 
-	for _, route := range m.routes {
-		if matchRoute(route, url) {
-			call(route, w, r)
+	if m.cleanPath {
+		if p := m.requestPath(r); cleanPath(p) != p {
+			m.redirect(w, r, cleanPath(p))
 			return
 		}
 	}
 
-	h := m.errorHandlers[http.StatusNotFound]
-	h(w, r)
+	r, pathVersion, result := m.matchVersion(r)
+
+	if isPreflight(r) && m.servePreflight(w, r) {
+		return
+	}
+
+	if result.redirect != "" {
+		if pathVersion != "" {
+			result.redirect = "/" + pathVersion + result.redirect
+		}
+
+		m.redirect(w, r, result.redirect)
+		return
+	}
+
+	if result.route == nil {
+		if result.status == http.StatusMethodNotAllowed {
+			w.Header().Set("Allow", strings.Join(result.allowed, ", "))
+		}
+
+		m.serveError(w, r, result.status)
+		return
+	}
+
+	state := stateFromRequest(r)
+	state.route = result.route
+
+	if len(result.route.produces) > 0 {
+		addVary(w.Header(), "Accept")
+	}
+
+	if m.metrics != nil {
+		state.endInFlight = m.metrics.begin(result.route, r.Method)
+		defer state.releaseInFlight()
+	}
+
+	setHeaders(w, result.route.defaultHeaders)
+
+	if !m.applyCORS(result.route, w, r) {
+		return
+	}
+
+	gh, version, status := m.versionHandler(result.route, r, pathVersion)
+	if status != 0 {
+		m.serveError(w, r, status)
+		return
+	}
+
+	r, ok := m.checkBody(result.route, w, r)
+	if !ok {
+		return
+	}
+
+	m.serveVersion(result.route, gh, version, w, r)
 }
@@ -4,7 +4,74 @@ import (
 	"net/http"
 )
 
+const (
+	// AllErrors registers a catch-all error handler
+	AllErrors = 0
+	// ClientErrors registers an error handler for every 4xx status
+	ClientErrors = -4
+	// ServerErrors registers an error handler for every 5xx status
+	ServerErrors = -5
+)
+
+// errorClass returns the key of the class handler for the status, statuses
+// outside of 4xx and 5xx have no class.
+func errorClass(status int) int {
+	if status >= 400 && status <= 599 {
+		return -(status / 100)
+	}
+
+	return AllErrors
+}
+
 // DefaultNotFoundHandler - The default handler for NotFound errors
 func DefaultNotFoundHandler(w http.ResponseWriter, r *http.Request) {
-	http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	defaultError(w, r, http.StatusNotFound)
+}
+
+// DefaultForbiddenHandler - The default handler for Forbidden errors
+func DefaultForbiddenHandler(w http.ResponseWriter, r *http.Request) {
+	defaultError(w, r, http.StatusForbidden)
+}
+
+// DefaultMethodNotAllowedHandler - The default handler for MethodNotAllowed errors
+func DefaultMethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	defaultError(w, r, http.StatusMethodNotAllowed)
+}
+
+// DefaultNotAcceptableHandler - The default handler for NotAcceptable errors
+func DefaultNotAcceptableHandler(w http.ResponseWriter, r *http.Request) {
+	defaultError(w, r, http.StatusNotAcceptable)
+}
+
+// DefaultRequestEntityTooLargeHandler - The default handler for RequestEntityTooLarge errors
+func DefaultRequestEntityTooLargeHandler(w http.ResponseWriter, r *http.Request) {
+	defaultError(w, r, http.StatusRequestEntityTooLarge)
+}
+
+// DefaultUnsupportedMediaTypeHandler - The default handler for UnsupportedMediaType errors
+func DefaultUnsupportedMediaTypeHandler(w http.ResponseWriter, r *http.Request) {
+	defaultError(w, r, http.StatusUnsupportedMediaType)
+}
+
+// DefaultTooManyRequestsHandler - The default handler for TooManyRequests errors
+func DefaultTooManyRequestsHandler(w http.ResponseWriter, r *http.Request) {
+	defaultError(w, r, http.StatusTooManyRequests)
+}
+
+// DefaultInternalServerErrorHandler - The default handler for InternalServerError errors
+func DefaultInternalServerErrorHandler(w http.ResponseWriter, r *http.Request) {
+	defaultError(w, r, http.StatusInternalServerError)
+}
+
+// Error responds to the request with the error handler registered for the
+// status on the mux serving the request, it lets middleware and handlers
+// answer with the same error responses as the mux. Requests that aren't
+// served by a mux get the status text.
+func Error(w http.ResponseWriter, r *http.Request, status int) {
+	if state := stateFromRequest(r); state != nil && state.mux != nil {
+		state.mux.serveError(w, r, status)
+		return
+	}
+
+	http.Error(w, http.StatusText(status), status)
 }
@@ -1,42 +1,130 @@
 package mux
 
-// logger is an interface that should be capable of satisfying
-// most common log interfaces? This lets the mux log what happens
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Logger is an interface that should be capable of satisfying
+// most common log interfaces. This lets the mux log what happens
 // if a logger is provided by the consumer.
-type logger interface {
+type Logger interface {
 	Info(string, ...interface{})
 	Warn(string, ...interface{})
 	Debug(string, ...interface{})
 	Error(string, ...interface{})
 }
 
+// StructuredLogger is a leveled logger that takes a message and a list
+// of alternating keys and values. When the logger registered with the
+// mux implements StructuredLogger the mux logs fields through it instead
+// of formatting them into the message.
+type StructuredLogger interface {
+	Log(level Level, msg string, keyvals ...interface{})
+}
+
+// Level is the severity of a log entry, the values match the levels
+// of log/slog.
+type Level int
+
 const (
-	infoLevel = iota
-	warnLevel
-	debugLevel
-	errorLevel
+	// LevelDebug is used for route matching decisions
+	LevelDebug Level = -4
+	// LevelInfo is used for access logs, this is the default threshold
+	LevelInfo Level = 0
+	// LevelWarn is used for replaced routes
+	LevelWarn Level = 4
+	// LevelError is used for failures the mux can't recover from
+	LevelError Level = 8
 )
 
+// String returns the name of the level
+func (l Level) String() string {
+	switch {
+	case l < LevelInfo:
+		return "DEBUG"
+	case l < LevelWarn:
+		return "INFO"
+	case l < LevelError:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// LogLevel sets the threshold for log entries, entries below the level
+// are dropped. The default threshold is LevelInfo.
+func (m *Mux) LogLevel(level Level) {
+	m.logLevel = level
+}
+
 // log is a wrapper around the logging functionality, this provides a common
 // place for mux to attempt logging and return if the consumer has not defined
-// a logger or log if a logger is provided
-func (m *Mux) log(level int, format string, data ...interface{}) {
+// a logger or log if a logger is provided.
+//
+// Structured loggers receive the key/value pairs as they are, other loggers
+// get them appended to the message as key=value.
+func (m *Mux) log(level Level, msg string, keyvals ...interface{}) {
+	if level < m.logLevel {
+		return
+	}
+
+	if m.structured != nil {
+		m.structured.Log(level, msg, keyvals...)
+		return
+	}
+
 	if m.logger == nil {
 		return
 	}
 
-	switch level {
-	case infoLevel:
+	m.logf(level, "%s", formatFields(msg, keyvals...))
+}
+
+// logRequest logs an entry about the request, the request ID is added to
+// the fields if the request has one.
+func (m *Mux) logRequest(r *http.Request, level Level, msg string, keyvals ...interface{}) {
+	if state := stateFromRequest(r); state != nil && state.requestID != "" {
+		keyvals = append(keyvals, "request_id", state.requestID)
+	}
+
+	m.log(level, msg, keyvals...)
+}
+
+// logf writes a formatted message to the logger
+func (m *Mux) logf(level Level, format string, data ...interface{}) {
+	switch {
+	case level < LevelInfo:
+		m.logger.Debug(format, data...)
+	case level < LevelWarn:
 		m.logger.Info(format, data...)
-		break
-	case warnLevel:
+	case level < LevelError:
 		m.logger.Warn(format, data...)
-		break
-	case debugLevel:
-		m.logger.Debug(format, data...)
-		break
-	case errorLevel:
+	default:
 		m.logger.Error(format, data...)
-		break
 	}
 }
+
+// formatFields appends the key/value pairs to the message as key=value,
+// values containing spaces or quotes are quoted.
+func formatFields(msg string, keyvals ...interface{}) string {
+	var b strings.Builder
+	b.WriteString(msg)
+
+	for i := 0; i < len(keyvals); i += 2 {
+		var value interface{} = "(MISSING)"
+		if i+1 < len(keyvals) {
+			value = keyvals[i+1]
+		}
+
+		v := fmt.Sprint(value)
+		if v == "" || strings.ContainsAny(v, " \"=") {
+			v = fmt.Sprintf("%q", v)
+		}
+
+		fmt.Fprintf(&b, " %v=%s", keyvals[i], v)
+	}
+
+	return b.String()
+}
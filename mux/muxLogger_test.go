@@ -0,0 +1,102 @@
+package mux
+
+import (
+	"bytes"
+	"log"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestStructuredLogging(t *testing.T) {
+	t.Log("Testing the mux logs fields through structured loggers.")
+
+	logger := NewTestLogger()
+	m := NewMux()
+	m.RegisterLogger(logger)
+	m.AccessLog(AccessLogCommon)
+	m.RegisterRoute("/users/{name}", writeBody("users"))
+
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/users/darwin", nil))
+
+	entries := logger.EntriesAt(LevelInfo)
+	if len(entries) != 2 {
+		t.Logf("[FAIL] :: Expected the registration and access log entries but got %d entries.", len(entries))
+		t.FailNow()
+	}
+
+	fields := entries[1].Fields
+	if fields["route"] != "/users/{name}" || fields["status"] != 200 || fields["method"] != "GET" {
+		t.Logf("[FAIL] :: Expected the route, status and method fields but got %+v.", fields)
+		t.Fail()
+	}
+
+	if len(logger.EntriesAt(LevelDebug)) != 0 {
+		t.Logf("[FAIL] :: Expected debug entries to be dropped by the default threshold.")
+		t.Fail()
+	}
+}
+
+var logLevelTests = []struct {
+	description   string
+	threshold     Level
+	expectedCount int
+}{{
+	description:   "Testing: The debug threshold keeps every entry.",
+	threshold:     LevelDebug,
+	expectedCount: 3,
+}, {
+	description:   "Testing: The warn threshold drops the info and debug entries.",
+	threshold:     LevelWarn,
+	expectedCount: 1,
+}, {
+	description:   "Testing: The error threshold drops every entry below error.",
+	threshold:     LevelError,
+	expectedCount: 0,
+}}
+
+func TestLogLevel(t *testing.T) {
+	t.Log("Testing log level thresholds.")
+
+	for i, test := range logLevelTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		logger := NewTestLogger()
+		m := NewMux()
+		m.LogLevel(test.threshold)
+		m.RegisterLogger(logger)
+
+		m.log(LevelDebug, "debug")
+		m.log(LevelWarn, "warn")
+
+		if count := len(logger.Entries()); count != test.expectedCount {
+			t.Logf("[FAIL] :: Expected %d entries but got %d entries.", test.expectedCount, count)
+			t.Fail()
+		}
+	}
+}
+
+func TestLoggerAdapters(t *testing.T) {
+	t.Log("Testing the slog and standard library adapters.")
+
+	var std bytes.Buffer
+	m := NewMux()
+	m.RegisterLogger(NewStdLogger(log.New(&std, "", 0)))
+	m.log(LevelWarn, "Route was replaced", "route", "/users/{name}")
+
+	if !strings.Contains(std.String(), "[WARN] Route was replaced route=/users/{name}") {
+		t.Logf("[FAIL] :: Expected a formatted warning but got \"%s\".", std.String())
+		t.Fail()
+	}
+
+	var structured bytes.Buffer
+	m = NewMux()
+	m.RegisterLogger(NewSlogLogger(slog.New(slog.NewJSONHandler(&structured, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+	m.log(LevelWarn, "Route was replaced", "route", "/users/{name}")
+
+	if !strings.Contains(structured.String(), `"level":"WARN","msg":"Route was replaced","route":"/users/{name}"`) {
+		t.Logf("[FAIL] :: Expected a structured warning but got \"%s\".", structured.String())
+		t.Fail()
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"reflect"
 	"strings"
 	"testing"
@@ -242,6 +243,42 @@ var variableListRetrievalTests = []struct {
 	requestURL:           "/int/123/size",
 	expectedValues:       []interface{}{int16(123)},
 	expectedErrorMessage: "",
+}, {
+	description:          "Testing: When an optional variable is missing from the request a nil value is returned for it.",
+	routeURL:             "/reports/{year: int}/{month: int?}",
+	requestURL:           "/reports/2024",
+	expectedValues:       []interface{}{2024, nil},
+	expectedErrorMessage: "",
+}, {
+	description:          "Testing: When an optional variable is in the request its value is returned.",
+	routeURL:             "/reports/{year: int}/{month: int?}",
+	requestURL:           "/reports/2024/10",
+	expectedValues:       []interface{}{2024, 10},
+	expectedErrorMessage: "",
+}, {
+	description:          "Testing: When a variable with a default value is missing from the request the default value is returned.",
+	routeURL:             "/list/{page: int=1}",
+	requestURL:           "/list",
+	expectedValues:       []interface{}{1},
+	expectedErrorMessage: "",
+}, {
+	description:          "Testing: When variables share a segment each variable matches lazily and the last variable takes the rest.",
+	routeURL:             "/files/{name}.{ext}",
+	requestURL:           "/files/archive.tar.gz",
+	expectedValues:       []interface{}{"archive", "tar.gz"},
+	expectedErrorMessage: "",
+}, {
+	description:          "Testing: When a greedy variable shares a segment it takes the longest value.",
+	routeURL:             "/downloads/{name+}.{ext}",
+	requestURL:           "/downloads/archive.tar.gz",
+	expectedValues:       []interface{}{"archive.tar", "gz"},
+	expectedErrorMessage: "",
+}, {
+	description:          "Testing: When a typed variable shares a segment with a literal the typed value is returned.",
+	routeURL:             "/v{version: int}/items/{id: int}",
+	requestURL:           "/v2/items/15",
+	expectedValues:       []interface{}{2, 15},
+	expectedErrorMessage: "",
 }}
 
 func TestVariableListRetrieval(t *testing.T) {
@@ -383,3 +420,475 @@ func TestVariableRetrievalByName(t *testing.T) {
 		}
 	}
 }
+
+var routeConditionTests = []struct {
+	description, method, requestURL string
+	headers                         map[string]string
+	setup                           func(m *Mux)
+	expectedResponse                response
+	expectedAllow                   string
+}{{
+	description: "Testing: A route restricted to a method is served for that method.",
+	method:      "GET",
+	requestURL:  "/conditions",
+	setup: func(m *Mux) {
+		must(m.RegisterRoute("/conditions", writeBody("get"))).Methods("GET")
+	},
+	expectedResponse: response{Body: "get", Code: http.StatusOK},
+}, {
+	description: "Testing: A route restricted to a method returns 405 with the allowed methods for other methods.",
+	method:      "DELETE",
+	requestURL:  "/conditions",
+	setup: func(m *Mux) {
+		must(m.RegisterRoute("/conditions", writeBody("get"))).Methods("GET")
+		must(m.RegisterRoute("/conditions", writeBody("post"))).Methods("POST")
+	},
+	expectedResponse: response{Body: http.StatusText(http.StatusMethodNotAllowed), Code: http.StatusMethodNotAllowed},
+	expectedAllow:    "GET, HEAD, POST",
+}, {
+	description: "Testing: Routes with conditions on the same path are selected by their method.",
+	method:      "POST",
+	requestURL:  "/conditions",
+	setup: func(m *Mux) {
+		must(m.RegisterRoute("/conditions", writeBody("get"))).Methods("GET")
+		must(m.RegisterRoute("/conditions", writeBody("post"))).Methods("POST")
+	},
+	expectedResponse: response{Body: "post", Code: http.StatusOK},
+}, {
+	description: "Testing: Routes on the same path are selected by their headers.",
+	method:      "GET",
+	requestURL:  "/conditions",
+	headers:     map[string]string{"Accept": "text/html, application/vnd.v2+json"},
+	setup: func(m *Mux) {
+		must(m.RegisterRoute("/conditions", writeBody("v1"))).Headers("Accept", "application/vnd.v1+json")
+		must(m.RegisterRoute("/conditions", writeBody("v2"))).Headers("Accept", "application/vnd.v2+json")
+	},
+	expectedResponse: response{Body: "v2", Code: http.StatusOK},
+}, {
+	description: "Testing: A route whose headers don't match returns 404.",
+	method:      "GET",
+	requestURL:  "/conditions",
+	setup: func(m *Mux) {
+		must(m.RegisterRoute("/conditions", writeBody("v1"))).Headers("Accept", "application/vnd.v1+json")
+	},
+	expectedResponse: response{Body: http.StatusText(http.StatusNotFound), Code: http.StatusNotFound},
+}, {
+	description: "Testing: A route whose headers don't match returns 404 even if the method doesn't match either.",
+	method:      "POST",
+	requestURL:  "/conditions",
+	setup: func(m *Mux) {
+		must(m.RegisterRoute("/conditions", writeBody("v1"))).Methods("GET").Headers("X-Version", "1")
+	},
+	expectedResponse: response{Body: http.StatusText(http.StatusNotFound), Code: http.StatusNotFound},
+}, {
+	description: "Testing: A route with a query condition is served when the query matches.",
+	method:      "GET",
+	requestURL:  "/conditions?format=csv",
+	setup: func(m *Mux) {
+		must(m.RegisterRoute("/conditions", writeBody("csv"))).Queries("format", "csv")
+		m.RegisterRoute("/conditions", writeBody("other"))
+	},
+	expectedResponse: response{Body: "csv", Code: http.StatusOK},
+}, {
+	description: "Testing: A route with a typed query variable isn't served when the value can't be converted.",
+	method:      "GET",
+	requestURL:  "/conditions?page=one",
+	setup: func(m *Mux) {
+		must(m.RegisterRoute("/conditions", writeBody("page"))).Queries("page", "{page: int}")
+	},
+	expectedResponse: response{Body: http.StatusText(http.StatusNotFound), Code: http.StatusNotFound},
+}, {
+	description: "Testing: A route restricted to https isn't served over http.",
+	method:      "GET",
+	requestURL:  "http://example.com/conditions",
+	setup: func(m *Mux) {
+		must(m.RegisterRoute("/conditions", writeBody("secure"))).Schemes("https")
+	},
+	expectedResponse: response{Body: http.StatusText(http.StatusNotFound), Code: http.StatusNotFound},
+}, {
+	description: "Testing: A route with a custom matcher is served when the matcher returns true.",
+	method:      "GET",
+	requestURL:  "/conditions",
+	headers:     map[string]string{"User-Agent": "gowt-test"},
+	setup: func(m *Mux) {
+		must(m.RegisterRoute("/conditions", writeBody("agent"))).MatcherFunc(func(r *http.Request) bool {
+			return strings.HasPrefix(r.UserAgent(), "gowt")
+		})
+	},
+	expectedResponse: response{Body: "agent", Code: http.StatusOK},
+}}
+
+func TestRouteConditions(t *testing.T) {
+	t.Log("Testing route selection with conditions.")
+
+	for i, test := range routeConditionTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		m := NewMux()
+		test.setup(m)
+
+		r := httptest.NewRequest(test.method, test.requestURL, nil)
+		for k, v := range test.headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		if w.Code != test.expectedResponse.Code {
+			t.Logf("[FAIL] :: Expected status code %d but got status code %d.", test.expectedResponse.Code, w.Code)
+			t.Fail()
+		}
+
+		body := strings.TrimSpace(w.Body.String())
+		if body != test.expectedResponse.Body {
+			t.Logf("[FAIL] :: Expected body \"%s\" but got body \"%s\".", test.expectedResponse.Body, body)
+			t.Fail()
+		}
+
+		if allow := w.Header().Get("Allow"); allow != test.expectedAllow {
+			t.Logf("[FAIL] :: Expected Allow header \"%s\" but got \"%s\".", test.expectedAllow, allow)
+			t.Fail()
+		}
+	}
+}
+
+func TestQueryVariableRetrieval(t *testing.T) {
+	t.Log("Testing getting a variable declared in a query condition.")
+
+	m := NewMux()
+	var value interface{}
+	var retrievalError error
+
+	route, _ := m.RegisterRoute("/report", func(w http.ResponseWriter, r *http.Request) {
+		value, retrievalError = m.GetVariableByName("fmt", r)
+	})
+	route.Queries("format", "{fmt}")
+
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/report?format=csv", nil))
+
+	if retrievalError != nil {
+		t.Logf("[FAIL] :: Got an unexpected error retrieving the variable: \"%s\".", retrievalError.Error())
+		t.Fail()
+	}
+
+	if value != "csv" {
+		t.Logf("[FAIL] :: Expected \"csv\" but got %+v instead.", value)
+		t.Fail()
+	}
+}
+
+// writeBody returns a handler that writes the body provided
+func writeBody(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) { fmt.Fprintln(w, body) }
+}
+
+// must returns the route and panics if registering it failed
+func must(route *Route, err error) *Route {
+	if err != nil {
+		panic(err)
+	}
+
+	return route
+}
+
+var pathPolicyTests = []struct {
+	description, route, requestURL string
+	setup                          func(m *Mux, route *Route)
+	expectedCode                   int
+	expectedLocation               string
+}{{
+	description:  "Testing: The lenient policy matches a request with a trailing \"/\" to a route without one.",
+	route:        "/policy",
+	requestURL:   "/policy/",
+	setup:        func(m *Mux, route *Route) {},
+	expectedCode: http.StatusOK,
+}, {
+	description:  "Testing: The strict policy doesn't match a request with a trailing \"/\" to a route without one.",
+	route:        "/policy",
+	requestURL:   "/policy/",
+	setup:        func(m *Mux, route *Route) { m.TrailingSlash(TrailingSlashStrict) },
+	expectedCode: http.StatusNotFound,
+}, {
+	description:  "Testing: The strict policy matches a request with a trailing \"/\" to a route registered with one.",
+	route:        "/policy/",
+	requestURL:   "/policy/",
+	setup:        func(m *Mux, route *Route) { m.TrailingSlash(TrailingSlashStrict) },
+	expectedCode: http.StatusOK,
+}, {
+	description:      "Testing: The redirect policy redirects to the form the route was registered with and keeps the query.",
+	route:            "/policy/",
+	requestURL:       "/policy?page=2",
+	setup:            func(m *Mux, route *Route) { m.TrailingSlash(TrailingSlashRedirect) },
+	expectedCode:     http.StatusMovedPermanently,
+	expectedLocation: "/policy/?page=2",
+}, {
+	description:      "Testing: The redirect policy keeps the client on the host when the request starts with \"//\".",
+	route:            "/{x}/",
+	requestURL:       "//evil.com/x",
+	setup:            func(m *Mux, route *Route) { m.TrailingSlash(TrailingSlashRedirect) },
+	expectedCode:     http.StatusMovedPermanently,
+	expectedLocation: "/x/",
+}, {
+	description: "Testing: A route policy overrides the mux policy and uses the redirect code set on the mux.",
+	route:       "/policy",
+	requestURL:  "/policy/",
+	setup: func(m *Mux, route *Route) {
+		m.TrailingSlash(TrailingSlashStrict)
+		m.RedirectCode(http.StatusPermanentRedirect)
+		route.TrailingSlash(TrailingSlashRedirect)
+	},
+	expectedCode:     http.StatusPermanentRedirect,
+	expectedLocation: "/policy",
+}, {
+	description:      "Testing: Path cleaning redirects to the path without empty, \".\" and \"..\" segments.",
+	route:            "/policy/clean",
+	requestURL:       "/policy//other/./../clean/",
+	setup:            func(m *Mux, route *Route) { m.CleanPath(true) },
+	expectedCode:     http.StatusMovedPermanently,
+	expectedLocation: "/policy/clean/",
+}, {
+	description:  "Testing: A request with an empty path doesn't panic.",
+	route:        "/",
+	requestURL:   "",
+	setup:        func(m *Mux, route *Route) {},
+	expectedCode: http.StatusOK,
+}}
+
+func TestPathPolicies(t *testing.T) {
+	t.Log("Testing trailing slash and path cleaning policies.")
+
+	for i, test := range pathPolicyTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		m := NewMux()
+		route := must(m.RegisterRoute(test.route, writeBody("policy")))
+		test.setup(m, route)
+
+		r := httptest.NewRequest("GET", "/", nil)
+		r.URL, _ = url.Parse(test.requestURL)
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		if w.Code != test.expectedCode {
+			t.Logf("[FAIL] :: Expected status code %d but got status code %d.", test.expectedCode, w.Code)
+			t.Fail()
+		}
+
+		if location := w.Header().Get("Location"); location != test.expectedLocation {
+			t.Logf("[FAIL] :: Expected location \"%s\" but got \"%s\".", test.expectedLocation, location)
+			t.Fail()
+		}
+	}
+}
+
+var encodedPathTests = []struct {
+	description, route, requestURL string
+	encoded                        bool
+	expectedCode                   int
+	expectedValue                  interface{}
+}{{
+	description:   "Testing: Without encoded paths an encoded \"/\" in a variable splits the segment and the route doesn't match.",
+	route:         "/objects/{key}",
+	requestURL:    "/objects/photos%2Fcat.png",
+	encoded:       false,
+	expectedCode:  http.StatusNotFound,
+	expectedValue: nil,
+}, {
+	description:   "Testing: With encoded paths an encoded \"/\" stays in the variable and the value is unescaped.",
+	route:         "/objects/{key}",
+	requestURL:    "/objects/photos%2Fcat.png",
+	encoded:       true,
+	expectedCode:  http.StatusOK,
+	expectedValue: "photos/cat.png",
+}, {
+	description:   "Testing: With encoded paths a unicode variable value is unescaped.",
+	route:         "/files/{key}",
+	requestURL:    "/files/%E6%97%A5%E6%9C%AC.txt",
+	encoded:       true,
+	expectedCode:  http.StatusOK,
+	expectedValue: "日本.txt",
+}, {
+	description:   "Testing: With encoded paths an encoded literal matches the literal in the route.",
+	route:         "/café/{key}",
+	requestURL:    "/caf%C3%A9/menu",
+	encoded:       true,
+	expectedCode:  http.StatusOK,
+	expectedValue: "menu",
+}}
+
+func TestEncodedPaths(t *testing.T) {
+	t.Log("Testing matching routes against encoded paths.")
+
+	for i, test := range encodedPathTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		m := NewMux()
+		m.UseEncodedPath(test.encoded)
+
+		var value interface{}
+		m.RegisterRoute(test.route, func(w http.ResponseWriter, r *http.Request) {
+			value, _ = m.GetVariableByName("key", r)
+		})
+
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", test.requestURL, nil))
+
+		if w.Code != test.expectedCode {
+			t.Logf("[FAIL] :: Expected status code %d but got status code %d.", test.expectedCode, w.Code)
+			t.Fail()
+		}
+
+		if value != test.expectedValue {
+			t.Logf("[FAIL] :: Expected %+v but got %+v instead.", test.expectedValue, value)
+			t.Fail()
+		}
+	}
+}
+
+var caseMatchingTests = []struct {
+	description, route, requestURL string
+	policy                         CasePolicy
+	expectedCode                   int
+	expectedLocation               string
+	expectedValue                  interface{}
+}{{
+	description:   "Testing: By default literals with a different case don't match.",
+	route:         "/Products/{name}",
+	requestURL:    "/products/Widget",
+	expectedCode:  http.StatusNotFound,
+	expectedValue: nil,
+}, {
+	description:   "Testing: Case insensitive matching matches literals with a different case and keeps the variable case.",
+	route:         "/Products/{name}",
+	requestURL:    "/PRODUCTS/Widget",
+	policy:        CaseInsensitive,
+	expectedCode:  http.StatusOK,
+	expectedValue: "Widget",
+}, {
+	description:   "Testing: Case insensitive matching matches routes without variables.",
+	route:         "/About/Team",
+	requestURL:    "/about/team",
+	policy:        CaseInsensitive,
+	expectedCode:  http.StatusOK,
+	expectedValue: nil,
+}, {
+	description:      "Testing: Case redirects send the request to the registered casing and keep the variable case.",
+	route:            "/Products/{name}/Reviews",
+	requestURL:       "/products/Widget/REVIEWS?page=2",
+	policy:           CaseRedirect,
+	expectedCode:     http.StatusMovedPermanently,
+	expectedLocation: "/Products/Widget/Reviews?page=2",
+	expectedValue:    nil,
+}, {
+	description:      "Testing: Case redirects use the registered casing for literals that share a segment with a variable.",
+	route:            "/Files/{name}.PDF",
+	requestURL:       "/files/Report.pdf",
+	policy:           CaseRedirect,
+	expectedCode:     http.StatusMovedPermanently,
+	expectedLocation: "/Files/Report.PDF",
+	expectedValue:    nil,
+}, {
+	description:   "Testing: Case redirects serve requests that already use the registered casing.",
+	route:         "/Products/{name}/Reviews",
+	requestURL:    "/Products/Widget/Reviews",
+	policy:        CaseRedirect,
+	expectedCode:  http.StatusOK,
+	expectedValue: "Widget",
+}}
+
+func TestCaseMatching(t *testing.T) {
+	t.Log("Testing case insensitive matching and canonical case redirects.")
+
+	for i, test := range caseMatchingTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		m := NewMux()
+		m.CaseMatching(test.policy)
+
+		var value interface{}
+		m.RegisterRoute(test.route, func(w http.ResponseWriter, r *http.Request) {
+			value, _ = m.GetVariableByName("name", r)
+		})
+
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", test.requestURL, nil))
+
+		if w.Code != test.expectedCode {
+			t.Logf("[FAIL] :: Expected status code %d but got status code %d.", test.expectedCode, w.Code)
+			t.Fail()
+		}
+
+		if location := w.Header().Get("Location"); location != test.expectedLocation {
+			t.Logf("[FAIL] :: Expected location \"%s\" but got \"%s\".", test.expectedLocation, location)
+			t.Fail()
+		}
+
+		if value != test.expectedValue {
+			t.Logf("[FAIL] :: Expected %+v but got %+v instead.", test.expectedValue, value)
+			t.Fail()
+		}
+	}
+}
+
+var defaultHeaderTests = []struct {
+	description, requestURL string
+	handler                 http.HandlerFunc
+	expectedHeaders         map[string]string
+}{{
+	description: "Testing: The mux default headers are added to responses from routes.",
+	requestURL:  "/headers",
+	handler:     writeBody("headers"),
+	expectedHeaders: map[string]string{
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "max-age=60",
+	},
+}, {
+	description: "Testing: The mux default headers are added to error responses.",
+	requestURL:  "/missing",
+	handler:     writeBody("headers"),
+	expectedHeaders: map[string]string{
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "no-store",
+	},
+}, {
+	description: "Testing: A handler can override and remove the default headers.",
+	requestURL:  "/headers",
+	handler: func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Del("X-Content-Type-Options")
+		fmt.Fprintln(w, "headers")
+	},
+	expectedHeaders: map[string]string{
+		"X-Content-Type-Options": "",
+		"Cache-Control":          "no-cache",
+	},
+}}
+
+func TestDefaultHeaders(t *testing.T) {
+	t.Log("Testing default response headers for the mux and routes.")
+
+	for i, test := range defaultHeaderTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		m := NewMux()
+		m.DefaultHeaders(http.Header{
+			"X-Content-Type-Options": {"nosniff"},
+			"Cache-Control":          {"no-store"},
+		})
+
+		route := must(m.RegisterRoute("/headers", test.handler))
+		route.DefaultHeaders(http.Header{"Cache-Control": {"max-age=60"}})
+
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", test.requestURL, nil))
+
+		for k, v := range test.expectedHeaders {
+			if header := w.Header().Get(k); header != v {
+				t.Logf("[FAIL] :: Expected %s header \"%s\" but got \"%s\".", k, v, header)
+				t.Fail()
+			}
+		}
+	}
+}
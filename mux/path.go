@@ -0,0 +1,303 @@
+package mux
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// TrailingSlashPolicy decides how a trailing "/" on the request path is
+// matched against the route that was registered.
+type TrailingSlashPolicy int
+
+const (
+	// TrailingSlashLenient ignores the trailing "/", "/x" and "/x/" are the
+	// same route. This is the default policy of a Mux.
+	TrailingSlashLenient TrailingSlashPolicy = iota + 1
+	// TrailingSlashStrict only matches requests whose trailing "/" is the
+	// same as the route's, "/x" and "/x/" are different routes.
+	TrailingSlashStrict
+	// TrailingSlashRedirect redirects requests that only differ from a route
+	// by the trailing "/" to the form the route was registered with.
+	TrailingSlashRedirect
+)
+
+// CasePolicy decides how the literal segments of a request path are matched
+// against the literal segments of the routes. Variable values always keep
+// the case they were requested with.
+type CasePolicy int
+
+const (
+	// CaseSensitive only matches literal segments with the same case. This is
+	// the default policy of a Mux.
+	CaseSensitive CasePolicy = iota + 1
+	// CaseInsensitive matches literal segments regardless of their case.
+	CaseInsensitive
+	// CaseRedirect matches literal segments regardless of their case and
+	// redirects the request to the casing the route was registered with.
+	CaseRedirect
+)
+
+// pathMatch is the result of matching a request path to a route
+type pathMatch int
+
+const (
+	noMatch pathMatch = iota
+	fullMatch
+	// redirectMatch means the path only differs from the route by the
+	// trailing "/" or the case of its literals and should be redirected
+	redirectMatch
+)
+
+// TrailingSlash sets the trailing slash policy used by every route that
+// doesn't set its own policy.
+func (m *Mux) TrailingSlash(policy TrailingSlashPolicy) {
+	m.slashPolicy = policy
+}
+
+// CleanPath enables redirecting requests whose path contains empty, "." or
+// ".." segments to the cleaned path, "/a//b/../c" is redirected to "/a/c".
+func (m *Mux) CleanPath(clean bool) {
+	m.cleanPath = clean
+}
+
+// RedirectCode sets the status code used when the mux redirects a request
+// to the canonical form of its path. It defaults to 301 Moved Permanently,
+// 308 Permanent Redirect can be used so clients keep the request method
+// and body.
+func (m *Mux) RedirectCode(code int) {
+	m.redirectCode = code
+}
+
+// CaseMatching sets the case policy used to match the literal segments of
+// the request path.
+func (m *Mux) CaseMatching(policy CasePolicy) {
+	m.casePolicy = policy
+}
+
+// UseEncodedPath matches routes against the escaped request path instead of
+// the decoded one. The path is split into segments before each segment is
+// unescaped, so an encoded "/" (%2F) stays inside its segment and can be
+// part of a variable value.
+func (m *Mux) UseEncodedPath(encoded bool) {
+	m.encodedPath = encoded
+}
+
+// TrailingSlash sets the trailing slash policy for the route, overriding
+// the policy of the mux.
+func (r *Route) TrailingSlash(policy TrailingSlashPolicy) *Route {
+	r.slashPolicy = policy
+
+	return r
+}
+
+// Subtree makes the route match its path and every path under it, such as
+// "/assets/css/site.css" for "/assets". A route that matches the request
+// exactly is preferred over a subtree route, the subtree route with the
+// longest path is preferred over the others and the trailing slash policy
+// doesn't apply to subtree routes.
+func (r *Route) Subtree() *Route {
+	r.subtree = true
+
+	return r
+}
+
+// depth returns the number of segments in the route's path
+func (r *Route) depth() int {
+	return len(cleanSlice(strings.Split(r.url, "/")))
+}
+
+// trailingSlashPolicy returns the policy that applies to the route
+func (m *Mux) trailingSlashPolicy(route *Route) TrailingSlashPolicy {
+	if route.slashPolicy != 0 {
+		return route.slashPolicy
+	}
+
+	if m.slashPolicy != 0 {
+		return m.slashPolicy
+	}
+
+	return TrailingSlashLenient
+}
+
+// requestPath returns the path of the request that is used for matching,
+// this is the escaped path when the mux uses encoded paths.
+func (m *Mux) requestPath(r *http.Request) string {
+	if m.encodedPath {
+		return r.URL.EscapedPath()
+	}
+
+	return r.URL.Path
+}
+
+// requestSegments returns the segments of the request path
+func (m *Mux) requestSegments(r *http.Request) []string {
+	return splitPath(m.requestPath(r), m.encodedPath)
+}
+
+// matchPath matches the request path to the route using the trailing
+// slash policy of the route and the case policy of the mux.
+func (m *Mux) matchPath(route *Route, requestPath string) pathMatch {
+	fold := m.casePolicy == CaseInsensitive || m.casePolicy == CaseRedirect
+
+	if route.subtree {
+		prefix := subtreePrefix(requestPath, route)
+		if !matchPathCase(*route, prefix, m.encodedPath, fold) {
+			return noMatch
+		}
+
+		if m.casePolicy == CaseRedirect && !matchPathCase(*route, prefix, m.encodedPath, false) {
+			return redirectMatch
+		}
+
+		return fullMatch
+	}
+	if !matchPathCase(*route, requestPath, m.encodedPath, fold) {
+		return noMatch
+	}
+
+	redirect := false
+
+	policy := m.trailingSlashPolicy(route)
+	if policy != TrailingSlashLenient && hasTrailingSlash(requestPath) != route.trailingSlash {
+		if policy != TrailingSlashRedirect {
+			return noMatch
+		}
+
+		redirect = true
+	}
+
+	if m.casePolicy == CaseRedirect && !matchPathCase(*route, requestPath, m.encodedPath, false) {
+		redirect = true
+	}
+
+	if redirect {
+		return redirectMatch
+	}
+
+	return fullMatch
+}
+
+// canonicalPath returns the request path in the form the route was
+// registered with. The literal segments are taken from the route, the
+// variable segments are kept as they were requested.
+func (m *Mux) canonicalPath(route *Route, requestPath string) string {
+	segments := cleanSlice(strings.Split(requestPath, "/"))
+	urlBlocks := cleanSlice(strings.Split(route.url, "/"))
+
+	for i, block := range urlBlocks {
+		if i >= len(segments) || isVariable(block) {
+			continue
+		}
+
+		literal := block
+		if isMixedSegment(block) {
+			segment := segments[i]
+			if m.encodedPath {
+				segment, _ = url.PathUnescape(segment)
+			}
+
+			literal = canonicalSegment(block, segment)
+		}
+
+		if m.encodedPath {
+			segments[i] = url.PathEscape(literal)
+		} else {
+			segments[i] = literal
+		}
+	}
+
+	canonical := "/" + strings.Join(segments, "/")
+
+	slash := hasTrailingSlash(requestPath)
+	if m.trailingSlashPolicy(route) == TrailingSlashRedirect {
+		slash = route.trailingSlash
+	}
+
+	if slash && canonical != "/" {
+		canonical += "/"
+	}
+
+	return canonical
+}
+
+// subtreePrefix returns the part of the request path with as many segments
+// as the route, the rest of the path is under the subtree.
+func subtreePrefix(requestPath string, route *Route) string {
+	segments := cleanSlice(strings.Split(requestPath, "/"))
+
+	if n := route.depth(); len(segments) > n {
+		segments = segments[:n]
+	}
+
+	return "/" + strings.Join(segments, "/")
+}
+
+// redirect sends the client to the path provided keeping the query, the
+// path is expected to be escaped when the mux uses encoded paths. Only the
+// path and query are sent and the path starts with a single "/" so the
+// client is never sent to another host.
+func (m *Mux) redirect(w http.ResponseWriter, r *http.Request, location string) {
+	code := m.redirectCode
+	if code == 0 {
+		code = http.StatusMovedPermanently
+	}
+
+	location = "/" + strings.TrimLeft(location, "/")
+
+	u := url.URL{RawQuery: r.URL.RawQuery}
+	u.Path = location
+	u.RawPath = ""
+
+	if m.encodedPath {
+		if unescaped, err := url.PathUnescape(location); err == nil {
+			u.Path = unescaped
+			u.RawPath = location
+		}
+	}
+
+	http.Redirect(w, r, u.String(), code)
+}
+
+// hasTrailingSlash reports if the path ends in a "/", the root path
+// doesn't count as having a trailing "/".
+func hasTrailingSlash(p string) bool {
+	return len(p) > 1 && p[len(p)-1] == '/'
+}
+
+// cleanPath returns the canonical form of the path by removing empty,
+// "." and ".." segments while keeping a trailing "/".
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+
+	if p[0] != '/' {
+		p = "/" + p
+	}
+
+	cleaned := path.Clean(p)
+	if hasTrailingSlash(p) && cleaned != "/" {
+		cleaned += "/"
+	}
+
+	return cleaned
+}
+
+// splitPath splits the path into its non-empty segments. An escaped path is
+// split before each segment is unescaped so encoded slashes don't create new
+// segments, segments that can't be unescaped are kept as they are.
+func splitPath(p string, escaped bool) []string {
+	segments := cleanSlice(strings.Split(p, "/"))
+
+	if escaped {
+		for i, s := range segments {
+			if unescaped, err := url.PathUnescape(s); err == nil {
+				segments[i] = unescaped
+			}
+		}
+	}
+
+	return segments
+}
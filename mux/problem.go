@@ -0,0 +1,154 @@
+package mux
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// ProblemConfig enables RFC 9457 problem details in the default error
+// handlers, see Mux.ProblemDetails.
+type ProblemConfig struct {
+	// TypeBase is prefixed to the status code to build the problem type,
+	// e.g. "https://example.com/problems/" gives
+	// "https://example.com/problems/404". The type is "about:blank" when
+	// it's empty.
+	TypeBase string
+	// Extensions returns extension members added to every problem
+	Extensions func(r *http.Request, status int) map[string]interface{}
+}
+
+// Problem is an RFC 9457 problem details object, extension members are
+// written next to the standard members.
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]interface{}
+}
+
+// MarshalJSON writes the problem with its extension members flattened,
+// extensions can't replace the standard members.
+func (p Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]interface{}, len(p.Extensions)+5)
+	for key, value := range p.Extensions {
+		members[key] = value
+	}
+
+	members["type"] = p.Type
+	members["title"] = p.Title
+	members["status"] = p.Status
+
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	}
+
+	return json.Marshal(members)
+}
+
+// ProblemDetails makes the default error handlers, and statuses without an
+// error handler, answer with application/problem+json bodies holding the
+// status, the request path as the instance and the request ID. The message
+// and code of an HTTPError returned by an error route become the detail and
+// a code member. Clients that prefer plain text over JSON still get the
+// status text.
+func (m *Mux) ProblemDetails(config ProblemConfig) {
+	m.problems = &config
+}
+
+// WriteProblem writes the problem as application/problem+json, or as plain
+// text for clients that prefer it.
+func WriteProblem(w http.ResponseWriter, r *http.Request, problem Problem) {
+	addVary(w.Header(), "Accept")
+
+	if !prefersProblemJSON(r.Header.Get("Accept")) {
+		text := problem.Title
+		if text == "" {
+			text = http.StatusText(problem.Status)
+		}
+
+		http.Error(w, text, problem.Status)
+		return
+	}
+
+	body, err := json.Marshal(problem)
+	if err != nil {
+		http.Error(w, http.StatusText(problem.Status), problem.Status)
+		return
+	}
+
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	w.Write(append(body, '\n'))
+}
+
+// defaultError is the response of the default error handlers, a problem
+// if the mux serving the request has problem details enabled and the
+// status text otherwise.
+func defaultError(w http.ResponseWriter, r *http.Request, status int) {
+	state := stateFromRequest(r)
+	if state == nil || state.mux == nil || state.mux.problems == nil {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	WriteProblem(w, r, newProblem(state.mux.problems, r, status))
+}
+
+// newProblem builds the problem for the request and status
+func newProblem(config *ProblemConfig, r *http.Request, status int) Problem {
+	problem := Problem{
+		Type:       "about:blank",
+		Title:      http.StatusText(status),
+		Status:     status,
+		Instance:   r.URL.Path,
+		Extensions: make(map[string]interface{}),
+	}
+
+	if config.TypeBase != "" {
+		problem.Type = config.TypeBase + strconv.Itoa(status)
+	}
+
+	var httpErr *HTTPError
+	if errors.As(GetError(r), &httpErr) {
+		problem.Detail = httpErr.Message
+		if httpErr.Code != "" {
+			problem.Extensions["code"] = httpErr.Code
+		}
+	}
+
+	if id := GetRequestID(r); id != "" {
+		problem.Extensions["request_id"] = id
+	}
+
+	if config.Extensions != nil {
+		for key, value := range config.Extensions(r, status) {
+			problem.Extensions[key] = value
+		}
+	}
+
+	return problem
+}
+
+// prefersProblemJSON reports if the Accept header prefers JSON over plain
+// text, application/json is taken to accept problem+json. JSON is used
+// when neither is accepted since an error can't be answered with a 406.
+func prefersProblemJSON(accept string) bool {
+	ranges := parseAccept(accept)
+
+	jsonWeight := acceptWeight(ranges, "application/problem+json")
+	if weight := acceptWeight(ranges, "application/json"); weight > jsonWeight {
+		jsonWeight = weight
+	}
+
+	return jsonWeight >= acceptWeight(ranges, "text/plain")
+}
@@ -0,0 +1,145 @@
+package mux
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var problemTests = []struct {
+	description, method, requestURL, accept string
+	expectedStatus                          int
+	expectedContentType                     string
+	expectedMembers                         map[string]interface{}
+	expectedBody                            string
+}{{
+	description:         "Testing: A 404 is answered with a problem.",
+	method:              "GET",
+	requestURL:          "/missing",
+	accept:              "application/json",
+	expectedStatus:      http.StatusNotFound,
+	expectedContentType: "application/problem+json",
+	expectedMembers: map[string]interface{}{
+		"type": "https://example.com/problems/404", "title": "Not Found", "status": float64(404),
+		"instance": "/missing", "request_id": "req-1", "service": "users",
+	},
+}, {
+	description:         "Testing: A 405 is answered with a problem and keeps its Allow header.",
+	method:              "DELETE",
+	requestURL:          "/users",
+	expectedStatus:      http.StatusMethodNotAllowed,
+	expectedContentType: "application/problem+json",
+	expectedMembers:     map[string]interface{}{"title": "Method Not Allowed", "status": float64(405)},
+}, {
+	description:         "Testing: An HTTPError gives the problem its detail and code without a registered handler.",
+	method:              "POST",
+	requestURL:          "/users",
+	accept:              "application/problem+json",
+	expectedStatus:      http.StatusConflict,
+	expectedContentType: "application/problem+json",
+	expectedMembers:     map[string]interface{}{"detail": "Name is taken", "code": "duplicate", "status": float64(409)},
+}, {
+	description:         "Testing: An error route's 500 is answered with a problem without the error text.",
+	method:              "PUT",
+	requestURL:          "/users",
+	expectedStatus:      http.StatusInternalServerError,
+	expectedContentType: "application/problem+json",
+	expectedMembers:     map[string]interface{}{"title": "Internal Server Error", "status": float64(500)},
+}, {
+	description:         "Testing: Clients that prefer plain text get the status text.",
+	method:              "GET",
+	requestURL:          "/missing",
+	accept:              "text/plain, application/json;q=0.5",
+	expectedStatus:      http.StatusNotFound,
+	expectedContentType: "text/plain; charset=utf-8",
+	expectedBody:        "Not Found\n",
+}}
+
+func TestProblemDetails(t *testing.T) {
+	t.Log("Testing the default error handlers write problem details.")
+
+	for i, test := range problemTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		m := NewMux()
+		m.Use(RequestID(RequestIDConfig{}))
+		m.ProblemDetails(ProblemConfig{
+			TypeBase: "https://example.com/problems/",
+			Extensions: func(r *http.Request, status int) map[string]interface{} {
+				return map[string]interface{}{"service": "users"}
+			},
+		})
+
+		must(m.RegisterErrorRoute("/users", func(w http.ResponseWriter, r *http.Request) error {
+			if r.Method == "POST" {
+				return &HTTPError{Status: http.StatusConflict, Code: "duplicate", Message: "Name is taken"}
+			}
+			return errors.New("database password rejected")
+		})).Methods("POST", "PUT")
+
+		r := httptest.NewRequest(test.method, test.requestURL, nil)
+		r.Header.Set("X-Request-ID", "req-1")
+		if test.accept != "" {
+			r.Header.Set("Accept", test.accept)
+		}
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		if w.Code != test.expectedStatus || w.Header().Get("Content-Type") != test.expectedContentType {
+			t.Logf("[FAIL] :: Expected %d \"%s\" but got %d \"%s\".", test.expectedStatus, test.expectedContentType, w.Code, w.Header().Get("Content-Type"))
+			t.Fail()
+		}
+
+		if test.expectedBody != "" {
+			if w.Body.String() != test.expectedBody {
+				t.Logf("[FAIL] :: Expected the body \"%s\" but got \"%s\".", test.expectedBody, w.Body.String())
+				t.Fail()
+			}
+			continue
+		}
+
+		var members map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &members); err != nil {
+			t.Logf("[FAIL] :: Expected a JSON body but got \"%s\".", w.Body.String())
+			t.Fail()
+			continue
+		}
+
+		for key, expected := range test.expectedMembers {
+			if members[key] != expected {
+				t.Logf("[FAIL] :: Expected the member \"%s\" to be %v but got %v.", key, expected, members[key])
+				t.Fail()
+			}
+		}
+
+		if detail, _ := members["detail"].(string); test.expectedStatus == http.StatusInternalServerError && detail != "" {
+			t.Logf("[FAIL] :: Expected no detail for a server error but got \"%s\".", detail)
+			t.Fail()
+		}
+
+		if test.expectedStatus == http.StatusMethodNotAllowed && w.Header().Get("Allow") != "POST, PUT" {
+			t.Logf("[FAIL] :: Expected the Allow header but got \"%s\".", w.Header().Get("Allow"))
+			t.Fail()
+		}
+	}
+}
+
+func TestProblemDetailsDisabled(t *testing.T) {
+	t.Log("Testing the default error handlers write plain text by default.")
+
+	m := NewMux()
+
+	r := httptest.NewRequest("GET", "/missing", nil)
+	r.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+
+	m.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound || w.Body.String() != "Not Found\n" {
+		t.Logf("[FAIL] :: Expected the plain text 404 but got %d \"%s\".", w.Code, w.Body.String())
+		t.Fail()
+	}
+}
@@ -0,0 +1,234 @@
+package mux
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// KeyFunc returns the key a request is rate limited by, requests with an
+// empty key aren't limited.
+type KeyFunc func(r *http.Request) string
+
+// RateLimitConfig configures the RateLimit middleware
+type RateLimitConfig struct {
+	// Rate is the number of requests per second a key is allowed, it
+	// defaults to 1.
+	Rate float64
+	// Burst is the number of requests a key can make at once, it defaults
+	// to 1.
+	Burst int
+	// Key returns the key a request is limited by, it defaults to KeyByIP
+	Key KeyFunc
+	// Store holds the buckets, it defaults to a new MemoryStore
+	Store RateLimitStore
+}
+
+// RateLimitResult is the state of a bucket after a request took a token
+type RateLimitResult struct {
+	// Allowed is false if the bucket was empty
+	Allowed bool
+	// Remaining is the number of whole tokens left in the bucket
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next token is available, it is only
+	// set when the request isn't allowed.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore holds the token buckets of the RateLimit middleware, it can
+// be replaced with a store that is shared between servers. Take removes a
+// token from the bucket for the key, the bucket starts out full and is
+// refilled at rate tokens per second up to burst tokens.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, rate float64, burst int) (RateLimitResult, error)
+}
+
+// RateLimit returns token bucket rate limiting middleware. Requests that are
+// over the limit are answered with the 429 error handler and a Retry-After
+// header, and every limited response carries the RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers.
+//
+// Used on a route or group each route has its own buckets, used on the mux
+// the buckets are shared by every route. Errors from the store are logged
+// and the request is allowed.
+func RateLimit(config RateLimitConfig) Middleware {
+	if config.Rate <= 0 || math.IsNaN(config.Rate) {
+		config.Rate = 1
+	}
+
+	if config.Burst < 1 {
+		config.Burst = 1
+	}
+
+	if config.Key == nil {
+		config.Key = KeyByIP
+	}
+
+	if config.Store == nil {
+		config.Store = NewMemoryStore()
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := config.Key(r)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if route := routeFromRequest(r); route != nil {
+				key = route.template() + " " + key
+			}
+
+			result, err := config.Store.Take(r.Context(), key, config.Rate, config.Burst)
+			if err != nil {
+				if state := stateFromRequest(r); state != nil && state.mux != nil {
+					state.mux.logRequest(r, LevelError, "The rate limit store failed, the request is allowed", "error", err.Error())
+				}
+
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(config.Burst))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+			if !result.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				Error(w, r, http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// KeyByIP limits requests by the client's IP address, the address is taken
+// from the connection so it can't be spoofed with headers.
+func KeyByIP(r *http.Request) string {
+	return "ip:" + remoteHost(r)
+}
+
+// KeyByHeader limits requests by the value of the header, requests without
+// the header are limited by IP.
+func KeyByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		if value := r.Header.Get(name); value != "" {
+			return "header:" + value
+		}
+
+		return KeyByIP(r)
+	}
+}
+
+// KeyByAPIKey limits requests by the API key in the X-API-Key header or the
+// api_key query parameter, requests without a key are limited by IP.
+func KeyByAPIKey(r *http.Request) string {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		key = r.URL.Query().Get("api_key")
+	}
+
+	if key == "" {
+		return KeyByIP(r)
+	}
+
+	return "apikey:" + key
+}
+
+// ceilSeconds rounds the duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// MemoryStore is a RateLimitStore that keeps the buckets in memory, full
+// buckets are removed periodically so it doesn't grow without bound.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	takes   int
+
+	// now returns the current time, it is replaced in tests
+	now func() time.Time
+}
+
+// bucket is a token bucket, tokens is the number of tokens when it was
+// last updated.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	rate    float64
+	burst   int
+}
+
+// cleanupInterval is the number of takes between removing full buckets
+const cleanupInterval = 1000
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// Take removes a token from the bucket for the key
+func (s *MemoryStore) Take(ctx context.Context, key string, rate float64, burst int) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	s.takes++
+	if s.takes%cleanupInterval == 0 {
+		for k, b := range s.buckets {
+			if b.refill(now) >= float64(b.burst) {
+				delete(s.buckets, k)
+			}
+		}
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), updated: now}
+		s.buckets[key] = b
+	}
+
+	b.rate, b.burst = rate, burst
+	b.tokens = b.refill(now)
+	b.updated = now
+
+	result := RateLimitResult{Allowed: b.tokens >= 1}
+	if result.Allowed {
+		b.tokens--
+	} else {
+		result.RetryAfter = tokenWait(1-b.tokens, rate)
+	}
+
+	result.Remaining = int(b.tokens)
+	result.Reset = tokenWait(float64(burst)-b.tokens, rate)
+
+	return result, nil
+}
+
+// refill returns the number of tokens in the bucket at the time
+func (b *bucket) refill(now time.Time) float64 {
+	return math.Min(float64(b.burst), b.tokens+now.Sub(b.updated).Seconds()*b.rate)
+}
+
+// tokenWait returns the time it takes to refill the tokens
+func tokenWait(tokens, rate float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+
+	if rate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+
+	return time.Duration(tokens / rate * float64(time.Second))
+}
@@ -0,0 +1,180 @@
+package mux
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var rateLimitTests = []struct {
+	description      string
+	key              KeyFunc
+	requests         []rateLimitRequest
+	expectedStatuses []int
+}{{
+	description:      "Testing: Requests over the burst are rejected.",
+	requests:         []rateLimitRequest{{url: "/a"}, {url: "/a"}, {url: "/a"}},
+	expectedStatuses: []int{200, 200, 429},
+}, {
+	description:      "Testing: Clients have their own buckets.",
+	requests:         []rateLimitRequest{{url: "/a"}, {url: "/a"}, {url: "/a", remoteAddr: "10.0.0.2:1234"}},
+	expectedStatuses: []int{200, 200, 200},
+}, {
+	description:      "Testing: Routes have their own buckets.",
+	requests:         []rateLimitRequest{{url: "/a"}, {url: "/a"}, {url: "/b"}},
+	expectedStatuses: []int{200, 200, 200},
+}, {
+	description:      "Testing: Tokens are refilled over time.",
+	requests:         []rateLimitRequest{{url: "/a"}, {url: "/a"}, {url: "/a", after: time.Second}},
+	expectedStatuses: []int{200, 200, 200},
+}, {
+	description: "Testing: Requests can be limited by a header.",
+	key:         KeyByHeader("X-Tenant"),
+	requests: []rateLimitRequest{
+		{url: "/a", header: "one"}, {url: "/a", header: "one"}, {url: "/a", header: "two"}, {url: "/a", header: "one"},
+	},
+	expectedStatuses: []int{200, 200, 200, 429},
+}, {
+	description: "Testing: Requests can be limited by API key.",
+	key:         KeyByAPIKey,
+	requests: []rateLimitRequest{
+		{url: "/a?api_key=k1"}, {url: "/a?api_key=k1"}, {url: "/a?api_key=k1"}, {url: "/a?api_key=k2"},
+	},
+	expectedStatuses: []int{200, 200, 429, 200},
+}, {
+	description:      "Testing: Requests with an empty key aren't limited.",
+	key:              func(r *http.Request) string { return "" },
+	requests:         []rateLimitRequest{{url: "/a"}, {url: "/a"}, {url: "/a"}},
+	expectedStatuses: []int{200, 200, 200},
+}}
+
+// rateLimitRequest is a request made by the rate limit tests, after is how
+// long after the previous request it is made.
+type rateLimitRequest struct {
+	url, remoteAddr, header string
+	after                   time.Duration
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Log("Testing requests are rate limited per route and client.")
+
+	for i, test := range rateLimitTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		now := time.Now()
+		store := NewMemoryStore()
+		store.now = func() time.Time { return now }
+
+		m := NewMux()
+		m.RegisterErrorHandler(http.StatusTooManyRequests, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte("slow down"))
+		})
+
+		group := m.Group("").Use(RateLimit(RateLimitConfig{Rate: 1, Burst: 2, Key: test.key, Store: store}))
+		group.RegisterRoute("/a", func(w http.ResponseWriter, r *http.Request) {})
+		group.RegisterRoute("/b", func(w http.ResponseWriter, r *http.Request) {})
+
+		for j, request := range test.requests {
+			now = now.Add(request.after)
+
+			r := httptest.NewRequest("GET", request.url, nil)
+			if request.remoteAddr != "" {
+				r.RemoteAddr = request.remoteAddr
+			}
+			if request.header != "" {
+				r.Header.Set("X-Tenant", request.header)
+			}
+			w := httptest.NewRecorder()
+
+			m.ServeHTTP(w, r)
+
+			if w.Code != test.expectedStatuses[j] {
+				t.Logf("[FAIL] :: Expected request %d to get %d but got %d.", j+1, test.expectedStatuses[j], w.Code)
+				t.Fail()
+			}
+
+			if w.Code == http.StatusTooManyRequests && (w.Body.String() != "slow down" || w.Header().Get("Retry-After") != "1") {
+				t.Logf("[FAIL] :: Expected the error handler with Retry-After 1 but got \"%s\" and %v.", w.Body.String(), w.Header())
+				t.Fail()
+			}
+		}
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	t.Log("Testing the RateLimit headers describe the bucket.")
+
+	now := time.Now()
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	m := NewMux()
+	must(m.RegisterRoute("/a", func(w http.ResponseWriter, r *http.Request) {})).
+		Use(RateLimit(RateLimitConfig{Rate: 0.5, Burst: 3, Store: store}))
+
+	expected := []struct{ remaining, reset string }{{"2", "2"}, {"1", "4"}, {"0", "6"}, {"0", "6"}}
+	for i, e := range expected {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", "/a", nil))
+
+		if w.Header().Get("RateLimit-Limit") != "3" || w.Header().Get("RateLimit-Remaining") != e.remaining || w.Header().Get("RateLimit-Reset") != e.reset {
+			t.Logf("[FAIL] :: Expected request %d to have remaining %s and reset %s but got %v.", i+1, e.remaining, e.reset, w.Header())
+			t.Fail()
+		}
+	}
+}
+
+func TestRateLimitDefaults(t *testing.T) {
+	t.Log("Testing a non-positive rate and burst default to one request per second.")
+
+	now := time.Now()
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	m := NewMux()
+	must(m.RegisterRoute("/a", func(w http.ResponseWriter, r *http.Request) {})).
+		Use(RateLimit(RateLimitConfig{Rate: 0, Burst: -1, Store: store}))
+
+	expected := []struct {
+		status     int
+		retryAfter string
+	}{{http.StatusOK, ""}, {http.StatusTooManyRequests, "1"}}
+	for i, e := range expected {
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", "/a", nil))
+
+		if w.Code != e.status || w.Header().Get("Retry-After") != e.retryAfter || w.Header().Get("RateLimit-Limit") != "1" {
+			t.Logf("[FAIL] :: Expected request %d to get %d with Retry-After \"%s\" but got %d and %v.", i+1, e.status, e.retryAfter, w.Code, w.Header())
+			t.Fail()
+		}
+	}
+}
+
+// failingStore is a RateLimitStore that always fails
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, rate float64, burst int) (RateLimitResult, error) {
+	return RateLimitResult{}, errors.New("store unavailable")
+}
+
+func TestRateLimitStoreError(t *testing.T) {
+	t.Log("Testing requests are allowed when the store fails.")
+
+	logger := NewTestLogger()
+	m := NewMux()
+	m.RegisterLogger(logger)
+	must(m.RegisterRoute("/a", func(w http.ResponseWriter, r *http.Request) {})).
+		Use(RateLimit(RateLimitConfig{Rate: 1, Store: failingStore{}}))
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/a", nil))
+
+	if w.Code != http.StatusOK || len(logger.EntriesAt(LevelError)) != 1 {
+		t.Logf("[FAIL] :: Expected the request to be allowed and the error logged but got %d and %v.", w.Code, logger.Entries())
+		t.Fail()
+	}
+}
@@ -0,0 +1,210 @@
+package mux
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrNotAcceptable is returned by Render when none of the media types it
+// can produce are accepted by the client.
+var ErrNotAcceptable = errors.New("None of the available media types are acceptable")
+
+// Encoder writes values in a media type for Render
+type Encoder interface {
+	Encode(w io.Writer, v interface{}) error
+}
+
+// EncoderFunc lets a function be used as an Encoder
+type EncoderFunc func(w io.Writer, v interface{}) error
+
+// Encode calls the function
+func (f EncoderFunc) Encode(w io.Writer, v interface{}) error {
+	return f(w, v)
+}
+
+// Produces restricts the route to requests that accept one of the media
+// types. Routes that share a path can produce different types, the first
+// route registered that produces an accepted type is selected and a request
+// that only failed to match because of its Accept header is answered with
+// 406. The media types are also the ones Render chooses from for the route.
+func (r *Route) Produces(mediaTypes ...string) *Route {
+	for _, mediaType := range mediaTypes {
+		r.produces = appendUnique(r.produces, strings.ToLower(mediaType))
+	}
+
+	return r
+}
+
+// RegisterEncoder registers the encoder Render uses for the media type,
+// encoders are preferred in the order they are registered when the client
+// accepts several types equally. JSON, XML and plain text encoders are
+// registered by NewMux.
+//
+// The function returns true if an existing encoder was replaced
+func (m *Mux) RegisterEncoder(mediaType string, encoder Encoder) bool {
+	mediaType = strings.ToLower(mediaType)
+
+	_, ok := m.encoders[mediaType]
+	if !ok {
+		m.encoderTypes = append(m.encoderTypes, mediaType)
+	}
+
+	m.encoders[mediaType] = encoder
+
+	return ok
+}
+
+// defaultEncoders returns the JSON, XML and plain text encoders and their
+// media types in order of preference.
+func defaultEncoders() (map[string]Encoder, []string) {
+	encoders := map[string]Encoder{
+		"application/json": EncoderFunc(func(w io.Writer, v interface{}) error {
+			return json.NewEncoder(w).Encode(v)
+		}),
+		"application/xml": EncoderFunc(func(w io.Writer, v interface{}) error {
+			return xml.NewEncoder(w).Encode(v)
+		}),
+		"text/plain": EncoderFunc(func(w io.Writer, v interface{}) error {
+			_, err := fmt.Fprint(w, v)
+			return err
+		}),
+	}
+
+	return encoders, []string{"application/json", "application/xml", "text/plain"}
+}
+
+// Render writes the value with the status using the encoder for the media
+// type the Accept header prefers. The types are limited to the ones the
+// route produces if it called Produces. If no type is acceptable the 406
+// error handler is called and ErrNotAcceptable is returned, if encoding
+// fails nothing is written and the error is returned.
+//
+// Requests that aren't served by a mux are rendered with the encoders
+// NewMux registers.
+func Render(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	var encoders map[string]Encoder
+	var offers []string
+
+	if state := stateFromRequest(r); state != nil && state.mux != nil {
+		encoders, offers = state.mux.encoders, state.mux.encoderTypes
+
+		if state.route != nil && len(state.route.produces) > 0 {
+			offers = state.route.produces
+		}
+	} else {
+		encoders, offers = defaultEncoders()
+	}
+
+	addVary(w.Header(), "Accept")
+
+	mediaType := negotiateMediaType(r.Header.Get("Accept"), offers, encoders)
+	if mediaType == "" {
+		Error(w, r, http.StatusNotAcceptable)
+		return ErrNotAcceptable
+	}
+
+	var buf bytes.Buffer
+	if err := encoders[mediaType].Encode(&buf, v); err != nil {
+		return err
+	}
+
+	contentType := mediaType
+	if strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "json") || strings.HasSuffix(mediaType, "xml") {
+		contentType += "; charset=utf-8"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, err := w.Write(buf.Bytes())
+
+	return err
+}
+
+// acceptsAny reports if the Accept header accepts one of the media types
+func acceptsAny(accept string, mediaTypes []string) bool {
+	return negotiateMediaType(accept, mediaTypes, nil) != ""
+}
+
+// acceptRange is a media range from an Accept header
+type acceptRange struct {
+	mediaType string
+	weight    float64
+}
+
+// negotiateMediaType returns the offered media type the Accept header
+// prefers, ties are broken by the order of the offers. Offers without an
+// encoder are skipped if encoders is not nil. A missing Accept header
+// accepts everything.
+func negotiateMediaType(accept string, offers []string, encoders map[string]Encoder) string {
+	ranges := parseAccept(accept)
+
+	best, bestWeight := "", 0.0
+	for _, offer := range offers {
+		if encoders != nil && encoders[offer] == nil {
+			continue
+		}
+
+		weight := acceptWeight(ranges, offer)
+		if weight > bestWeight {
+			best, bestWeight = offer, weight
+		}
+	}
+
+	return best
+}
+
+// parseAccept parses the media ranges of an Accept header, the most
+// specific ranges come first.
+func parseAccept(accept string) []acceptRange {
+	if strings.TrimSpace(accept) == "" {
+		return []acceptRange{{mediaType: "*/*", weight: 1}}
+	}
+
+	var ranges []acceptRange
+	for _, entry := range strings.Split(accept, ",") {
+		params := strings.Split(entry, ";")
+		ar := acceptRange{mediaType: strings.ToLower(strings.TrimSpace(params[0])), weight: 1}
+
+		if ar.mediaType == "" {
+			continue
+		}
+
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					ar.weight = q
+				}
+			}
+		}
+
+		ranges = append(ranges, ar)
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return strings.Count(ranges[i].mediaType, "*") < strings.Count(ranges[j].mediaType, "*")
+	})
+
+	return ranges
+}
+
+// acceptWeight returns the weight of the most specific range matching the
+// media type, or 0 if no range matches.
+func acceptWeight(ranges []acceptRange, mediaType string) float64 {
+	for _, ar := range ranges {
+		if ar.mediaType == mediaType || ar.mediaType == "*/*" ||
+			(strings.HasSuffix(ar.mediaType, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(ar.mediaType, "*"))) {
+			return ar.weight
+		}
+	}
+
+	return 0
+}
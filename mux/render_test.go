@@ -0,0 +1,204 @@
+package mux
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var produceTests = []struct {
+	description, requestURL, accept string
+	expectedStatus                  int
+	expectedBody                    string
+}{{
+	description:    "Testing: The route producing the accepted type is selected.",
+	requestURL:     "/report",
+	accept:         "text/html",
+	expectedStatus: http.StatusOK,
+	expectedBody:   "html",
+}, {
+	description:    "Testing: The first route is selected when any type is accepted.",
+	requestURL:     "/report",
+	accept:         "*/*",
+	expectedStatus: http.StatusOK,
+	expectedBody:   "api",
+}, {
+	description:    "Testing: A more specific range overrides a wildcard.",
+	requestURL:     "/report",
+	accept:         "application/*;q=0, text/*",
+	expectedStatus: http.StatusOK,
+	expectedBody:   "html",
+}, {
+	description:    "Testing: A request accepting none of the types gets the 406 error handler.",
+	requestURL:     "/report",
+	accept:         "image/png",
+	expectedStatus: http.StatusNotAcceptable,
+	expectedBody:   "not acceptable",
+}, {
+	description:    "Testing: A type with a zero weight isn't accepted.",
+	requestURL:     "/report",
+	accept:         "text/html;q=0, application/*;q=0",
+	expectedStatus: http.StatusNotAcceptable,
+	expectedBody:   "not acceptable",
+}, {
+	description:    "Testing: A path no route matches still gets a 404.",
+	requestURL:     "/missing",
+	accept:         "image/png",
+	expectedStatus: http.StatusNotFound,
+	expectedBody:   "Not Found\n",
+}}
+
+func TestProduces(t *testing.T) {
+	t.Log("Testing routes are matched by the media types they produce.")
+
+	for i, test := range produceTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		m := NewMux()
+		m.RegisterErrorHandler(http.StatusNotAcceptable, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotAcceptable)
+			w.Write([]byte("not acceptable"))
+		})
+
+		must(m.RegisterRoute("/report", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("api"))
+		})).Produces("application/json", "application/xml")
+		must(m.RegisterRoute("/report", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("html"))
+		})).Produces("text/html")
+
+		r := httptest.NewRequest("GET", test.requestURL, nil)
+		r.Header.Set("Accept", test.accept)
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		if w.Code != test.expectedStatus || w.Body.String() != test.expectedBody {
+			t.Logf("[FAIL] :: Expected %d \"%s\" but got %d \"%s\".", test.expectedStatus, test.expectedBody, w.Code, w.Body.String())
+			t.Fail()
+		}
+
+		if w.Code == http.StatusOK && w.Header().Get("Vary") != "Accept" {
+			t.Logf("[FAIL] :: Expected Vary to be \"Accept\" but got \"%s\".", w.Header().Get("Vary"))
+			t.Fail()
+		}
+	}
+}
+
+// renderValue is the value rendered by the render tests
+type renderValue struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func (v renderValue) String() string {
+	return "name: " + v.Name
+}
+
+var renderTests = []struct {
+	description, requestURL, accept                string
+	expectedStatus                                 int
+	expectedContentType, expectedBody, expectedErr string
+}{{
+	description:         "Testing: JSON is rendered by default.",
+	requestURL:          "/any",
+	expectedStatus:      http.StatusCreated,
+	expectedContentType: "application/json; charset=utf-8",
+	expectedBody:        "{\"name\":\"gowt\"}\n",
+}, {
+	description:         "Testing: XML is rendered when it is preferred.",
+	requestURL:          "/any",
+	accept:              "application/json;q=0.5, application/xml",
+	expectedStatus:      http.StatusCreated,
+	expectedContentType: "application/xml; charset=utf-8",
+	expectedBody:        "<renderValue><name>gowt</name></renderValue>",
+}, {
+	description:         "Testing: Plain text uses the value's String method.",
+	requestURL:          "/any",
+	accept:              "text/plain",
+	expectedStatus:      http.StatusCreated,
+	expectedContentType: "text/plain; charset=utf-8",
+	expectedBody:        "name: gowt",
+}, {
+	description:         "Testing: Registered encoders can be rendered.",
+	requestURL:          "/any",
+	accept:              "text/csv",
+	expectedStatus:      http.StatusCreated,
+	expectedContentType: "text/csv; charset=utf-8",
+	expectedBody:        "name\ngowt\n",
+}, {
+	description:         "Testing: Only the types the route produces are rendered.",
+	requestURL:          "/xml",
+	accept:              "application/json, */*;q=0.1",
+	expectedStatus:      http.StatusCreated,
+	expectedContentType: "application/xml; charset=utf-8",
+	expectedBody:        "<renderValue><name>gowt</name></renderValue>",
+}, {
+	description:    "Testing: A type without an encoder gets the 406 error handler.",
+	requestURL:     "/any",
+	accept:         "image/png",
+	expectedStatus: http.StatusNotAcceptable,
+	expectedBody:   "Not Acceptable\n",
+	expectedErr:    ErrNotAcceptable.Error(),
+}}
+
+func TestRender(t *testing.T) {
+	t.Log("Testing values are rendered with the negotiated encoder.")
+
+	for i, test := range renderTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		m := NewMux()
+		m.RegisterEncoder("text/csv", EncoderFunc(func(w io.Writer, v interface{}) error {
+			_, err := io.WriteString(w, "name\n"+v.(renderValue).Name+"\n")
+			return err
+		}))
+
+		var renderErr error
+		render := func(w http.ResponseWriter, r *http.Request) {
+			renderErr = Render(w, r, http.StatusCreated, renderValue{Name: "gowt"})
+		}
+		m.RegisterRoute("/any", render)
+		must(m.RegisterRoute("/xml", render)).Produces("application/xml")
+
+		r := httptest.NewRequest("GET", test.requestURL, nil)
+		r.Header.Set("Accept", test.accept)
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		if w.Code != test.expectedStatus || w.Body.String() != test.expectedBody {
+			t.Logf("[FAIL] :: Expected %d \"%s\" but got %d \"%s\".", test.expectedStatus, test.expectedBody, w.Code, w.Body.String())
+			t.Fail()
+		}
+
+		if test.expectedContentType != "" && w.Header().Get("Content-Type") != test.expectedContentType {
+			t.Logf("[FAIL] :: Expected the content type \"%s\" but got \"%s\".", test.expectedContentType, w.Header().Get("Content-Type"))
+			t.Fail()
+		}
+
+		if (renderErr == nil) != (test.expectedErr == "") || (renderErr != nil && !strings.Contains(renderErr.Error(), test.expectedErr)) {
+			t.Logf("[FAIL] :: Expected the error \"%s\" but got \"%v\".", test.expectedErr, renderErr)
+			t.Fail()
+		}
+	}
+}
+
+func TestRenderWithoutMux(t *testing.T) {
+	t.Log("Testing values are rendered with the default encoders outside of a mux.")
+
+	m := NewMux()
+	m.RegisterEncoder("application/json", EncoderFunc(func(w io.Writer, v interface{}) error {
+		_, err := io.WriteString(w, "replaced")
+		return err
+	}))
+
+	w := httptest.NewRecorder()
+	err := Render(w, httptest.NewRequest("GET", "/", nil), http.StatusOK, renderValue{Name: "gowt"})
+
+	if err != nil || w.Body.String() != "{\"name\":\"gowt\"}\n" {
+		t.Logf("[FAIL] :: Expected the default JSON encoder but got \"%s\" and \"%v\".", w.Body.String(), err)
+		t.Fail()
+	}
+}
@@ -0,0 +1,85 @@
+package mux
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// maxRequestIDLength is the longest incoming request ID that is accepted,
+// longer IDs are replaced with a generated one.
+const maxRequestIDLength = 200
+
+// RequestIDConfig configures the RequestID middleware
+type RequestIDConfig struct {
+	// Header is the request and response header holding the ID, it
+	// defaults to "X-Request-ID".
+	Header string
+	// Generate returns a new ID for requests that don't have one, it
+	// defaults to 16 random bytes encoded as hex.
+	Generate func() string
+}
+
+// RequestID returns middleware that reads the request ID from the incoming
+// request, or generates one, and stores it in the request context. The ID is
+// echoed on the response, so error responses carry it too, and when the mux
+// serves the request the ID is added to every log entry for it.
+func RequestID(config RequestIDConfig) Middleware {
+	if config.Header == "" {
+		config.Header = "X-Request-ID"
+	}
+
+	if config.Generate == nil {
+		config.Generate = generateRequestID
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(config.Header)
+			if !validRequestID(id) {
+				id = config.Generate()
+			}
+
+			if state := stateFromRequest(r); state != nil {
+				state.requestID = id
+			}
+
+			w.Header().Set(config.Header, id)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+		})
+	}
+}
+
+// GetRequestID returns the ID stored by the RequestID middleware, an empty
+// string is returned if the request doesn't have an ID.
+func GetRequestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey).(string)
+	return id
+}
+
+// validRequestID checks that an incoming ID is safe to log and echo, it
+// has to be short and only contain printable ASCII characters.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// generateRequestID returns 16 random bytes encoded as hex
+func generateRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(b)
+}
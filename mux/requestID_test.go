@@ -0,0 +1,117 @@
+package mux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var requestIDTests = []struct {
+	description, requestURL, header, incomingID string
+	expectGenerated                             bool
+}{{
+	description: "Testing: An incoming request ID is kept and echoed on the response.",
+	requestURL:  "/ids",
+	incomingID:  "abc-123",
+}, {
+	description:     "Testing: A request ID is generated when the request doesn't have one.",
+	requestURL:      "/ids",
+	expectGenerated: true,
+}, {
+	description:     "Testing: An incoming request ID with unsafe characters is replaced.",
+	requestURL:      "/ids",
+	incomingID:      "bad id\n",
+	expectGenerated: true,
+}, {
+	description: "Testing: The request ID header name can be configured.",
+	requestURL:  "/ids",
+	header:      "X-Correlation-ID",
+	incomingID:  "correlation-1",
+}, {
+	description: "Testing: Error responses carry the request ID.",
+	requestURL:  "/missing",
+	incomingID:  "abc-404",
+}}
+
+func TestRequestID(t *testing.T) {
+	t.Log("Testing request ID generation and propagation.")
+
+	for i, test := range requestIDTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		header := test.header
+		if header == "" {
+			header = "X-Request-ID"
+		}
+
+		logger := NewTestLogger()
+		m := NewMux()
+		m.RegisterLogger(logger)
+		m.LogLevel(LevelDebug)
+		m.Use(RequestID(RequestIDConfig{Header: test.header}))
+
+		var handlerID string
+		m.RegisterRoute("/ids", func(w http.ResponseWriter, r *http.Request) {
+			handlerID = GetRequestID(r)
+		})
+
+		r := httptest.NewRequest("GET", test.requestURL, nil)
+		if test.incomingID != "" {
+			r.Header.Set(header, test.incomingID)
+		}
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		responseID := w.Header().Get(header)
+		if test.expectGenerated {
+			if len(responseID) != 32 || responseID == test.incomingID {
+				t.Logf("[FAIL] :: Expected a generated request ID but got \"%s\".", responseID)
+				t.Fail()
+			}
+		} else if responseID != test.incomingID {
+			t.Logf("[FAIL] :: Expected the request ID \"%s\" but got \"%s\".", test.incomingID, responseID)
+			t.Fail()
+		}
+
+		if test.requestURL == "/ids" && handlerID != responseID {
+			t.Logf("[FAIL] :: Expected the handler to see \"%s\" but it saw \"%s\".", responseID, handlerID)
+			t.Fail()
+		}
+
+		for _, entry := range logger.EntriesAt(LevelDebug) {
+			if entry.Fields["request_id"] != responseID {
+				t.Logf("[FAIL] :: Expected the log entry \"%s\" to carry the request ID but got %+v.", entry.Message, entry.Fields)
+				t.Fail()
+			}
+		}
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	t.Log("Testing mux middleware runs before route middleware in the order it was added.")
+
+	var order []string
+	record := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	m := NewMux()
+	m.Use(record("mux1"), record("mux2"))
+	must(m.RegisterRoute("/order", func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	})).Use(record("route"))
+
+	m.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/order", nil))
+
+	if got := strings.Join(order, ","); got != "mux1,mux2,route,handler" {
+		t.Logf("[FAIL] :: Expected \"mux1,mux2,route,handler\" but got \"%s\".", got)
+		t.Fail()
+	}
+}
@@ -2,6 +2,8 @@ package mux
 
 import (
 	"net/http"
+	"strings"
+	"time"
 )
 
 // Route - A Route Object, only the object itself is exposed
@@ -13,6 +15,20 @@ type Route struct {
 	allowedMethods []string
 	hasVariables   bool
 	variables      []variableInfo
+	matchers       []matcher
+	versions       map[string]routeVersion
+	cors           *CORS
+	defaultHeaders http.Header
+	middleware     []Middleware
+	timeout        time.Duration
+	group          *Group
+	noCompression  bool
+	maxBody        int64
+	consumes       []string
+	produces       []string
+	subtree        bool
+	trailingSlash  bool
+	slashPolicy    TrailingSlashPolicy
 }
 
 // gowtHandler wraps around http.Handler and http.HandlerFunc
@@ -22,3 +38,32 @@ type gowtHandler struct {
 	handler     http.Handler
 	handlerFunc http.HandlerFunc
 }
+
+// ServeHTTP sends the response writer and request to whichever handler
+// type has been initialized
+func (gh gowtHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if gh.handler == nil {
+		gh.handlerFunc(w, r)
+		return
+	}
+
+	gh.handler.ServeHTTP(w, r)
+}
+
+// template returns the route as it was registered, with a trailing "/"
+// only if the route was registered with one.
+func (r *Route) template() string {
+	if r.subtree {
+		return strings.TrimSuffix(r.url, "/") + "/*"
+	}
+
+	if r.trailingSlash {
+		return r.url + "/"
+	}
+
+	if r.url == "" {
+		return "/"
+	}
+
+	return r.url
+}
@@ -0,0 +1,172 @@
+package mux
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// segmentPart is a piece of a route segment that mixes literals and
+// variables, such as "{name}.{ext}" or "v{version: int}". Either the
+// literal or the variable is set.
+type segmentPart struct {
+	literal  string
+	variable *variableInfo
+}
+
+// segmentPatterns caches the compiled pattern of every mixed segment
+// by the source of its regular expression.
+var segmentPatterns sync.Map
+
+// kindPatterns are the patterns a variable value has to match for each
+// kind when the variable shares its segment with other text. Kinds not
+// listed match any text.
+var kindPatterns = map[string]string{
+	"int":    `[-+]?[0-9]+`,
+	"int8":   `[-+]?[0-9]+`,
+	"int16":  `[-+]?[0-9]+`,
+	"int32":  `[-+]?[0-9]+`,
+	"int64":  `[-+]?[0-9]+`,
+	"uint":   `[0-9]+`,
+	"uint8":  `[0-9]+`,
+	"uint16": `[0-9]+`,
+	"uint32": `[0-9]+`,
+	"uint64": `[0-9]+`,
+}
+
+// isMixedSegment reports if the block of the route contains variables
+// but isn't a single variable.
+func isMixedSegment(block string) bool {
+	return strings.Contains(block, "{") && !isVariable(block)
+}
+
+// parseSegment splits a block of the route into its literal and
+// variable parts.
+func parseSegment(block string) ([]segmentPart, error) {
+	var parts []segmentPart
+
+	for block != "" {
+		start := strings.Index(block, "{")
+		if start < 0 {
+			parts = append(parts, segmentPart{literal: block})
+			break
+		}
+
+		if start > 0 {
+			parts = append(parts, segmentPart{literal: block[:start]})
+		}
+
+		end := strings.Index(block[start:], "}")
+		if end < 0 {
+			return nil, errors.New("Missing '}' in route variable declaration")
+		}
+		end += start
+
+		info, err := getVariableInfo(block[start : end+1])
+		if err != nil {
+			return nil, err
+		}
+
+		if info.optional {
+			return nil, errors.New("Optional variables can't share a segment with other text")
+		}
+
+		parts = append(parts, segmentPart{variable: &info})
+		block = block[end+1:]
+	}
+
+	return parts, nil
+}
+
+// segmentPattern returns the compiled pattern for a mixed segment, each
+// variable is a capture group in the order it appears in the segment.
+//
+// Variables match lazily, taking the shortest value that lets the rest of
+// the segment match, unless they are declared greedy with a "+" such as
+// "{name+}" in which case they take the longest value.
+func segmentPattern(block string, fold bool) (*regexp.Regexp, error) {
+	parts, err := parseSegment(block)
+	if err != nil {
+		return nil, err
+	}
+
+	var source strings.Builder
+	if fold {
+		source.WriteString("(?i)")
+	}
+	source.WriteString("^")
+
+	for _, part := range parts {
+		if part.variable == nil {
+			source.WriteString(regexp.QuoteMeta(part.literal))
+			continue
+		}
+
+		pattern, ok := kindPatterns[part.variable.kind]
+		if !ok {
+			pattern = ".+"
+		}
+
+		if !part.variable.greedy {
+			pattern += "?"
+		}
+
+		source.WriteString("(" + pattern + ")")
+	}
+	source.WriteString("$")
+
+	if re, ok := segmentPatterns.Load(source.String()); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(source.String())
+	if err != nil {
+		return nil, err
+	}
+
+	segmentPatterns.Store(source.String(), re)
+
+	return re, nil
+}
+
+// matchMixedSegment matches a segment of the request to a mixed segment
+// of the route and returns the values of its variables.
+func matchMixedSegment(block, segment string, fold bool) ([]string, bool) {
+	re, err := segmentPattern(block, fold)
+	if err != nil {
+		return nil, false
+	}
+
+	values := re.FindStringSubmatch(segment)
+	if values == nil {
+		return nil, false
+	}
+
+	return values[1:], true
+}
+
+// canonicalSegment returns the segment of the request with the literal
+// parts of the mixed segment taken from the route.
+func canonicalSegment(block, segment string) string {
+	values, ok := matchMixedSegment(block, segment, true)
+	if !ok {
+		return segment
+	}
+
+	parts, _ := parseSegment(block)
+
+	var canonical strings.Builder
+	i := 0
+	for _, part := range parts {
+		if part.variable == nil {
+			canonical.WriteString(part.literal)
+			continue
+		}
+
+		canonical.WriteString(values[i])
+		i++
+	}
+
+	return canonical.String()
+}
@@ -0,0 +1,181 @@
+package mux
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// StaticOptions configures the files served by Mux.Static
+type StaticOptions struct {
+	// Index is the file served for a directory, it defaults to
+	// "index.html".
+	Index string
+	// Listing lists the files of directories without an index file, the
+	// 404 error handler is used for them otherwise.
+	Listing bool
+	// SPA serves the index file of the root directory for paths that don't
+	// exist so a single page app can route them itself.
+	SPA bool
+	// CacheControl maps file extensions such as ".js" to the Cache-Control
+	// header sent with them, the "" entry is used for other extensions.
+	CacheControl map[string]string
+}
+
+// Static serves the files of fsys, such as an embed.FS or os.DirFS, under
+// the prefix for GET and HEAD requests. Range and conditional requests are
+// supported. Paths that don't exist are answered with the 404 error
+// handler, unless the SPA option is set. The route is a subtree route so
+// other routes registered under the prefix are still matched first.
+func (m *Mux) Static(prefix string, fsys fs.FS, opts StaticOptions) (*Route, error) {
+	if opts.Index == "" {
+		opts.Index = "index.html"
+	}
+
+	route, err := m.RegisterHandler(prefix, staticHandler{mux: m, fsys: fsys, opts: opts})
+	if err != nil {
+		return nil, err
+	}
+
+	return route.Subtree().Methods(http.MethodGet), nil
+}
+
+// staticHandler serves the files of a Static route
+type staticHandler struct {
+	mux  *Mux
+	fsys fs.FS
+	opts StaticOptions
+}
+
+// ServeHTTP serves the file or directory the request path names under the
+// prefix, unknown paths get the root index in SPA mode or a 404.
+func (h staticHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := h.fileName(r)
+
+	info, err := fs.Stat(h.fsys, name)
+	if err != nil {
+		if h.opts.SPA {
+			h.serveFile(w, r, h.opts.Index)
+			return
+		}
+
+		h.mux.serveError(w, r, http.StatusNotFound)
+		return
+	}
+
+	if !info.IsDir() {
+		h.serveFile(w, r, name)
+		return
+	}
+
+	// the target is cleaned so a path such as "//host" can't become a
+	// redirect to another host
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		h.mux.redirect(w, r, strings.TrimSuffix(cleanPath(h.mux.requestPath(r)), "/")+"/")
+		return
+	}
+
+	index := path.Join(name, h.opts.Index)
+	if _, err := fs.Stat(h.fsys, index); err == nil {
+		h.serveFile(w, r, index)
+		return
+	}
+
+	if h.opts.Listing {
+		h.serveListing(w, r, name)
+		return
+	}
+
+	h.mux.serveError(w, r, http.StatusNotFound)
+}
+
+// fileName returns the name of the requested file in the file system, the
+// segments of the route's prefix are removed from the request path.
+func (h staticHandler) fileName(r *http.Request) string {
+	segments := cleanSlice(strings.Split(r.URL.Path, "/"))
+
+	if route := routeFromRequest(r); route != nil {
+		n := route.depth()
+		if n > len(segments) {
+			n = len(segments)
+		}
+		segments = segments[n:]
+	}
+
+	name := strings.TrimPrefix(path.Clean("/"+strings.Join(segments, "/")), "/")
+	if name == "" {
+		return "."
+	}
+
+	return name
+}
+
+// serveFile writes the file with http.ServeContent, which handles range
+// and conditional requests and sets the content type.
+func (h staticHandler) serveFile(w http.ResponseWriter, r *http.Request, name string) {
+	f, err := h.fsys.Open(name)
+	if err != nil {
+		h.mux.serveError(w, r, http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		h.mux.serveError(w, r, http.StatusNotFound)
+		return
+	}
+
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			h.mux.serveError(w, r, http.StatusInternalServerError)
+			return
+		}
+
+		content = bytes.NewReader(data)
+	}
+
+	if cache, ok := h.opts.CacheControl[path.Ext(name)]; ok {
+		w.Header().Set("Cache-Control", cache)
+	} else if cache, ok := h.opts.CacheControl[""]; ok {
+		w.Header().Set("Cache-Control", cache)
+	}
+
+	http.ServeContent(w, r, info.Name(), info.ModTime(), content)
+}
+
+// serveListing writes a HTML list of the files in the directory
+func (h staticHandler) serveListing(w http.ResponseWriter, r *http.Request, name string) {
+	entries, err := fs.ReadDir(h.fsys, name)
+	if err != nil {
+		h.mux.serveError(w, r, http.StatusInternalServerError)
+		return
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	fmt.Fprintln(w, "<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>")
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if entry.IsDir() {
+			entryName += "/"
+		}
+
+		link := url.URL{Path: entryName}
+		fmt.Fprintf(w, "<a href=\"%s\">%s</a>\n", link.String(), html.EscapeString(entryName))
+	}
+	fmt.Fprintln(w, "</pre>")
+}
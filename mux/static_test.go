@@ -0,0 +1,282 @@
+package mux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// staticFiles is the file system served by the static tests
+var staticFiles = fstest.MapFS{
+	"index.html":         {Data: []byte("<h1>app</h1>"), ModTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	"app.js":             {Data: []byte("console.log('app')"), ModTime: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	"css/site.css":       {Data: []byte("body{}")},
+	"docs/index.html":    {Data: []byte("<h1>docs</h1>")},
+	"images/logo.png":    {Data: []byte("png")},
+	"images/banner.png":  {Data: []byte("png")},
+	"downloads/file.txt": {Data: []byte("0123456789")},
+}
+
+var staticTests = []struct {
+	description, method, requestURL string
+	options                         StaticOptions
+	headers                         map[string]string
+	expectedStatus                  int
+	expectedBody                    string
+	expectedHeaders                 map[string]string
+}{{
+	description:     "Testing: A file under the prefix is served with its content type.",
+	method:          "GET",
+	requestURL:      "/assets/css/site.css",
+	expectedStatus:  http.StatusOK,
+	expectedBody:    "body{}",
+	expectedHeaders: map[string]string{"Content-Type": "text/css; charset=utf-8"},
+}, {
+	description:    "Testing: A directory is served with its index file.",
+	method:         "GET",
+	requestURL:     "/assets/docs/",
+	expectedStatus: http.StatusOK,
+	expectedBody:   "<h1>docs</h1>",
+}, {
+	description:     "Testing: A directory without a trailing slash is redirected.",
+	method:          "GET",
+	requestURL:      "/assets/docs",
+	expectedStatus:  http.StatusMovedPermanently,
+	expectedHeaders: map[string]string{"Location": "/assets/docs/"},
+}, {
+	description:    "Testing: A missing file gets the 404 error handler.",
+	method:         "GET",
+	requestURL:     "/assets/missing.js",
+	expectedStatus: http.StatusNotFound,
+	expectedBody:   "custom 404",
+}, {
+	description:    "Testing: A directory without an index isn't listed by default.",
+	method:         "GET",
+	requestURL:     "/assets/images/",
+	expectedStatus: http.StatusNotFound,
+	expectedBody:   "custom 404",
+}, {
+	description:    "Testing: A directory without an index is listed when listing is on.",
+	method:         "GET",
+	requestURL:     "/assets/images/",
+	options:        StaticOptions{Listing: true},
+	expectedStatus: http.StatusOK,
+	expectedBody: "<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n" +
+		"<a href=\"banner.png\">banner.png</a>\n<a href=\"logo.png\">logo.png</a>\n</pre>\n",
+}, {
+	description:    "Testing: A missing path serves the root index in SPA mode.",
+	method:         "GET",
+	requestURL:     "/assets/users/42",
+	options:        StaticOptions{SPA: true},
+	expectedStatus: http.StatusOK,
+	expectedBody:   "<h1>app</h1>",
+}, {
+	description:     "Testing: A range request gets part of the file.",
+	method:          "GET",
+	requestURL:      "/assets/downloads/file.txt",
+	headers:         map[string]string{"Range": "bytes=2-4"},
+	expectedStatus:  http.StatusPartialContent,
+	expectedBody:    "234",
+	expectedHeaders: map[string]string{"Content-Range": "bytes 2-4/10"},
+}, {
+	description:    "Testing: An unmodified file gets a 304.",
+	method:         "GET",
+	requestURL:     "/assets/app.js",
+	headers:        map[string]string{"If-Modified-Since": time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC).Format(http.TimeFormat)},
+	expectedStatus: http.StatusNotModified,
+}, {
+	description:     "Testing: Cache headers are set by extension.",
+	method:          "GET",
+	requestURL:      "/assets/app.js",
+	options:         StaticOptions{CacheControl: map[string]string{".js": "public, max-age=31536000", "": "no-cache"}},
+	expectedStatus:  http.StatusOK,
+	expectedBody:    "console.log('app')",
+	expectedHeaders: map[string]string{"Cache-Control": "public, max-age=31536000"},
+}, {
+	description:     "Testing: The default cache header is used for other extensions.",
+	method:          "GET",
+	requestURL:      "/assets/css/site.css",
+	options:         StaticOptions{CacheControl: map[string]string{".js": "public, max-age=31536000", "": "no-cache"}},
+	expectedStatus:  http.StatusOK,
+	expectedBody:    "body{}",
+	expectedHeaders: map[string]string{"Cache-Control": "no-cache"},
+}, {
+	description:    "Testing: Routes under the prefix are matched before the files.",
+	method:         "GET",
+	requestURL:     "/assets/version",
+	expectedStatus: http.StatusOK,
+	expectedBody:   "v1",
+}, {
+	description:    "Testing: Paths can't leave the file system.",
+	method:         "GET",
+	requestURL:     "/assets/../../etc/passwd",
+	expectedStatus: http.StatusNotFound,
+	expectedBody:   "custom 404",
+}, {
+	description:     "Testing: Other methods are answered with 405.",
+	method:          "POST",
+	requestURL:      "/assets/app.js",
+	expectedStatus:  http.StatusMethodNotAllowed,
+	expectedHeaders: map[string]string{"Allow": "GET, HEAD"},
+}}
+
+func TestStatic(t *testing.T) {
+	t.Log("Testing static files are served under a prefix.")
+
+	for i, test := range staticTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		m := NewMux()
+		m.RegisterErrorHandler(http.StatusNotFound, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("custom 404"))
+		})
+
+		if _, err := m.Static("/assets", staticFiles, test.options); err != nil {
+			t.Logf("[FAIL] :: Expected no error but got \"%s\".", err)
+			t.Fail()
+			continue
+		}
+		m.RegisterRoute("/assets/version", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("v1"))
+		})
+
+		r := httptest.NewRequest(test.method, test.requestURL, nil)
+		r.URL.Path = test.requestURL
+		for key, value := range test.headers {
+			r.Header.Set(key, value)
+		}
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		if w.Code != test.expectedStatus {
+			t.Logf("[FAIL] :: Expected the status %d but got %d.", test.expectedStatus, w.Code)
+			t.Fail()
+		}
+
+		if test.expectedBody != "" && w.Body.String() != test.expectedBody {
+			t.Logf("[FAIL] :: Expected the body \"%s\" but got \"%s\".", test.expectedBody, w.Body.String())
+			t.Fail()
+		}
+
+		for key, expected := range test.expectedHeaders {
+			if got := w.Header().Get(key); got != expected {
+				t.Logf("[FAIL] :: Expected the header %s to be \"%s\" but got \"%s\".", key, expected, got)
+				t.Fail()
+			}
+		}
+	}
+}
+
+// rootFiles is the file system served at the root by the static tests
+var rootFiles = fstest.MapFS{
+	"index.html":            {Data: []byte("<h1>root</h1>")},
+	"docs/index.html":       {Data: []byte("<h1>docs</h1>")},
+	"evil.com/x/index.html": {Data: []byte("<h1>x</h1>")},
+}
+
+var staticRootTests = []struct {
+	description, method, requestURL string
+	expectedStatus                  int
+	expectedLocation, expectedAllow string
+}{{
+	description:    "Testing: The root is served with its index file.",
+	method:         "GET",
+	requestURL:     "/",
+	expectedStatus: http.StatusOK,
+}, {
+	description:      "Testing: A directory redirect doesn't keep a leading \"//\".",
+	method:           "GET",
+	requestURL:       "//docs",
+	expectedStatus:   http.StatusMovedPermanently,
+	expectedLocation: "/docs/",
+}, {
+	description:      "Testing: A directory named like a host isn't redirected to the host.",
+	method:           "GET",
+	requestURL:       "//evil.com/x",
+	expectedStatus:   http.StatusMovedPermanently,
+	expectedLocation: "/evil.com/x/",
+}, {
+	description:    "Testing: Other methods on a path the root only matches as a subtree get a 404.",
+	method:         "POST",
+	requestURL:     "/nothing/here",
+	expectedStatus: http.StatusNotFound,
+}, {
+	description:    "Testing: Other methods on the root itself get a 405.",
+	method:         "POST",
+	requestURL:     "/",
+	expectedStatus: http.StatusMethodNotAllowed,
+	expectedAllow:  "GET, HEAD",
+}}
+
+func TestStaticRoot(t *testing.T) {
+	t.Log("Testing static files are served at the root.")
+
+	for i, test := range staticRootTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		m := NewMux()
+		if _, err := m.Static("/", rootFiles, StaticOptions{}); err != nil {
+			t.Logf("[FAIL] :: Expected no error but got \"%s\".", err)
+			t.Fail()
+			continue
+		}
+
+		r := httptest.NewRequest(test.method, "/", nil)
+		r.URL.Path = test.requestURL
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		if w.Code != test.expectedStatus {
+			t.Logf("[FAIL] :: Expected the status %d but got %d.", test.expectedStatus, w.Code)
+			t.Fail()
+		}
+
+		if location := w.Header().Get("Location"); location != test.expectedLocation {
+			t.Logf("[FAIL] :: Expected the location \"%s\" but got \"%s\".", test.expectedLocation, location)
+			t.Fail()
+		}
+
+		if allow := w.Header().Get("Allow"); allow != test.expectedAllow {
+			t.Logf("[FAIL] :: Expected the Allow header \"%s\" but got \"%s\".", test.expectedAllow, allow)
+			t.Fail()
+		}
+	}
+}
+
+func TestStaticLongestPrefix(t *testing.T) {
+	t.Log("Testing the static route with the longest prefix serves the request.")
+
+	spa := fstest.MapFS{"index.html": {Data: []byte("<h1>spa</h1>")}}
+	assets := fstest.MapFS{"app.js": {Data: []byte("console.log('app')")}}
+
+	for i, rootFirst := range []bool{true, false} {
+		t.Logf("[ %02d ] Testing: The root is registered first: %t.", i+1, rootFirst)
+
+		m := NewMux()
+		if rootFirst {
+			m.Static("/", spa, StaticOptions{SPA: true})
+			m.Static("/assets", assets, StaticOptions{})
+		} else {
+			m.Static("/assets", assets, StaticOptions{})
+			m.Static("/", spa, StaticOptions{SPA: true})
+		}
+
+		for _, test := range []struct{ requestURL, expectedBody string }{
+			{"/assets/app.js", "console.log('app')"},
+			{"/users/42", "<h1>spa</h1>"},
+		} {
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, httptest.NewRequest("GET", test.requestURL, nil))
+
+			if w.Code != http.StatusOK || w.Body.String() != test.expectedBody {
+				t.Logf("[FAIL] :: Expected \"%s\" for %s but got %d \"%s\".", test.expectedBody, test.requestURL, w.Code, w.Body.String())
+				t.Fail()
+			}
+		}
+	}
+}
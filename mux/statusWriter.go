@@ -0,0 +1,85 @@
+package mux
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// statusWriter wraps a http.ResponseWriter and records the status code and
+// the number of bytes written so the mux can report on the response.
+type statusWriter struct {
+	http.ResponseWriter
+
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+// newStatusWriter wraps the response writer
+func newStatusWriter(w http.ResponseWriter) *statusWriter {
+	return &statusWriter{ResponseWriter: w}
+}
+
+// WriteHeader records the status code before writing it, informational
+// status codes are passed on without being recorded.
+func (w *statusWriter) WriteHeader(code int) {
+	if !w.wroteHeader && (code < 100 || code > 199 || code == http.StatusSwitchingProtocols) {
+		w.status = code
+		w.wroteHeader = true
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write records the number of bytes written, a 200 status is recorded if
+// no status was written before.
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+
+	return n, err
+}
+
+// Flush sends any buffered data to the client if the wrapped response
+// writer supports flushing.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		if !w.wroteHeader {
+			w.WriteHeader(http.StatusOK)
+		}
+
+		f.Flush()
+	}
+}
+
+// Hijack lets the handler take over the connection if the wrapped response
+// writer supports it.
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("The response writer doesn't support hijacking")
+	}
+
+	return h.Hijack()
+}
+
+// Unwrap returns the wrapped response writer for http.ResponseController
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Status returns the status code of the response, 200 is returned if the
+// handler didn't write a status since that's what the server sends.
+func (w *statusWriter) Status() int {
+	if !w.wroteHeader {
+		return http.StatusOK
+	}
+
+	return w.status
+}
@@ -0,0 +1,201 @@
+package mux
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Timeout sets the default time limit for requests served by a route, the
+// limit is added to the request context as a deadline. Requests that
+// exceed it are answered with the 503 error handler. A duration of 0, the
+// default, disables the limit.
+//
+// The limit applies to the route's handler, the group and route middleware
+// runs around it without a limit. Responses of routes with a time limit are
+// buffered until the handler returns, so they can't be flushed or hijacked.
+func (m *Mux) Timeout(d time.Duration) {
+	m.timeout = d
+}
+
+// Timeout sets the time limit for requests served by the route, it
+// overrides the mux default. A negative duration disables the limit for
+// the route.
+func (r *Route) Timeout(d time.Duration) *Route {
+	r.timeout = d
+
+	return r
+}
+
+// timeoutWriter buffers the response of a handler with a time limit,
+// writes after the limit is exceeded fail with http.ErrHandlerTimeout.
+type timeoutWriter struct {
+	mu sync.Mutex
+
+	header      http.Header
+	buf         bytes.Buffer
+	code        int
+	wroteHeader bool
+	timedOut    bool
+}
+
+// Header returns the buffered header that's sent if the handler returns
+// before the limit.
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.header
+}
+
+// WriteHeader records the status code, it's ignored once the limit has
+// been exceeded.
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut || tw.wroteHeader {
+		return
+	}
+
+	tw.code = code
+	tw.wroteHeader = true
+}
+
+// Write buffers the body, it fails with http.ErrHandlerTimeout once the
+// limit has been exceeded.
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+
+	if !tw.wroteHeader {
+		tw.code = http.StatusOK
+		tw.wroteHeader = true
+	}
+
+	return tw.buf.Write(b)
+}
+
+// writeTo sends the buffered response, the caller must hold the lock
+func (tw *timeoutWriter) writeTo(w http.ResponseWriter) {
+	header := w.Header()
+	for key := range header {
+		delete(header, key)
+	}
+	for key, values := range tw.header {
+		header[key] = values
+	}
+
+	if !tw.wroteHeader {
+		tw.code = http.StatusOK
+	}
+
+	w.WriteHeader(tw.code)
+	w.Write(tw.buf.Bytes())
+}
+
+// routeTimeout returns the time limit for the route
+func (m *Mux) routeTimeout(route *Route) time.Duration {
+	if route.timeout != 0 {
+		return route.timeout
+	}
+
+	return m.timeout
+}
+
+// timeoutHandler applies the route's time limit to the handler. Only the
+// handler runs on its own goroutine, the middleware around it runs on the
+// goroutine serving the request since it can change the request state.
+func (m *Mux) timeoutHandler(route *Route, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.serveTimeout(route, h, w, r)
+	})
+}
+
+// serveTimeout serves the request with the route's time limit, the handler
+// runs in its own goroutine and its response is only sent if it returns
+// before the limit.
+func (m *Mux) serveTimeout(route *Route, h http.Handler, w http.ResponseWriter, r *http.Request) {
+	d := m.routeTimeout(route)
+	if d <= 0 {
+		h.ServeHTTP(w, r)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), d)
+	defer cancel()
+	r = r.WithContext(ctx)
+
+	tw := &timeoutWriter{header: w.Header().Clone()}
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	panicked := make(chan interface{}, 1)
+
+	go func() {
+		defer close(finished)
+		defer func() {
+			if p := recover(); p != nil {
+				panicked <- p
+			}
+		}()
+
+		h.ServeHTTP(tw, r)
+		close(done)
+	}()
+
+	select {
+	case p := <-panicked:
+		panic(p)
+	case <-done:
+		tw.mu.Lock()
+		defer tw.mu.Unlock()
+
+		tw.writeTo(w)
+	case <-ctx.Done():
+		tw.mu.Lock()
+		defer tw.mu.Unlock()
+
+		// the handler may have returned as the limit was reached, its
+		// response is sent rather than a 503
+		select {
+		case p := <-panicked:
+			panic(p)
+		case <-done:
+			tw.writeTo(w)
+			return
+		default:
+		}
+
+		tw.timedOut = true
+
+		endInFlight := func() {}
+		if state := stateFromRequest(r); state != nil && state.endInFlight != nil {
+			endInFlight = state.endInFlight
+			state.endInFlight = nil
+		}
+
+		// the handler is still running, it stays in flight until it returns
+		// and a panic is logged since it can no longer reach the server
+		go func() {
+			<-finished
+
+			select {
+			case p := <-panicked:
+				m.logRequest(r, LevelError, "The handler panicked after exceeding the route's time limit", "route", route.template(), "panic", fmt.Sprint(p))
+			default:
+			}
+
+			endInFlight()
+		}()
+
+		if ctx.Err() == context.DeadlineExceeded {
+			m.logRequest(r, LevelWarn, "The request exceeded the route's time limit", "route", route.template(), "timeout", d.String())
+		}
+
+		m.serveError(w, r, http.StatusServiceUnavailable)
+	}
+}
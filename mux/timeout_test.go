@@ -0,0 +1,195 @@
+package mux
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// errorLogger sends every error log line to the channel, it's safe to use
+// from the goroutine of a timed out handler.
+type errorLogger chan string
+
+func (l errorLogger) Info(string, ...interface{})  {}
+func (l errorLogger) Warn(string, ...interface{})  {}
+func (l errorLogger) Debug(string, ...interface{}) {}
+func (l errorLogger) Error(format string, data ...interface{}) {
+	l <- fmt.Sprintf(format, data...)
+}
+
+var timeoutTests = []struct {
+	description                 string
+	muxTimeout, routeTimeout    time.Duration
+	handlerDelay                time.Duration
+	expectedStatus              int
+	expectedBody, expectedError string
+}{{
+	description:    "Testing: A handler that finishes in time is served.",
+	muxTimeout:     time.Second,
+	expectedStatus: http.StatusCreated,
+	expectedBody:   "done",
+}, {
+	description:    "Testing: A handler that exceeds the mux timeout is answered with the error handler.",
+	muxTimeout:     10 * time.Millisecond,
+	handlerDelay:   time.Second,
+	expectedStatus: http.StatusServiceUnavailable,
+	expectedBody:   "timed out",
+}, {
+	description:    "Testing: The route timeout overrides the mux timeout.",
+	muxTimeout:     10 * time.Millisecond,
+	routeTimeout:   time.Second,
+	handlerDelay:   20 * time.Millisecond,
+	expectedStatus: http.StatusCreated,
+	expectedBody:   "done",
+}, {
+	description:    "Testing: A negative route timeout disables the mux timeout.",
+	muxTimeout:     10 * time.Millisecond,
+	routeTimeout:   -1,
+	handlerDelay:   20 * time.Millisecond,
+	expectedStatus: http.StatusCreated,
+	expectedBody:   "done",
+}, {
+	description:    "Testing: A route timeout applies without a mux timeout.",
+	routeTimeout:   10 * time.Millisecond,
+	handlerDelay:   time.Second,
+	expectedStatus: http.StatusServiceUnavailable,
+	expectedBody:   "timed out",
+}}
+
+func TestTimeout(t *testing.T) {
+	t.Log("Testing requests are limited by the route and mux timeouts.")
+
+	for i, test := range timeoutTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		m := NewMux()
+		m.Timeout(test.muxTimeout)
+		m.RegisterErrorHandler(http.StatusServiceUnavailable, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("timed out"))
+		})
+
+		route := must(m.RegisterRoute("/work", func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-time.After(test.handlerDelay):
+			case <-r.Context().Done():
+				// a handler returning as the limit is reached is still served
+				time.Sleep(10 * time.Millisecond)
+			}
+
+			w.Header().Set("X-Handler", "work")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte("done"))
+		}))
+		route.Timeout(test.routeTimeout)
+
+		w := httptest.NewRecorder()
+		m.ServeHTTP(w, httptest.NewRequest("GET", "/work", nil))
+
+		if w.Code != test.expectedStatus || w.Body.String() != test.expectedBody {
+			t.Logf("[FAIL] :: Expected %d \"%s\" but got %d \"%s\".", test.expectedStatus, test.expectedBody, w.Code, w.Body.String())
+			t.Fail()
+		}
+
+		if test.expectedStatus == http.StatusCreated && w.Header().Get("X-Handler") != "work" {
+			t.Logf("[FAIL] :: Expected the handler's headers to be sent but got %v.", w.Header())
+			t.Fail()
+		}
+	}
+}
+
+func TestTimeoutWritesAfterDeadline(t *testing.T) {
+	t.Log("Testing writes after the timeout fail and don't reach the response.")
+
+	m := NewMux()
+
+	result := make(chan error, 1)
+	route := must(m.RegisterRoute("/work", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+
+		_, err := w.Write([]byte("late"))
+		result <- err
+	}))
+	route.Timeout(10 * time.Millisecond)
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/work", nil))
+
+	if err := <-result; err != http.ErrHandlerTimeout {
+		t.Logf("[FAIL] :: Expected the late write to fail with \"%v\" but got \"%v\".", http.ErrHandlerTimeout, err)
+		t.Fail()
+	}
+
+	if w.Code != http.StatusServiceUnavailable || w.Body.String() != http.StatusText(http.StatusServiceUnavailable)+"\n" {
+		t.Logf("[FAIL] :: Expected a 503 response but got %d \"%s\".", w.Code, w.Body.String())
+		t.Fail()
+	}
+}
+
+func TestTimeoutPanicAfterDeadline(t *testing.T) {
+	t.Log("Testing a panic after the timeout is logged.")
+
+	logger := make(errorLogger, 1)
+	m := NewMux()
+	m.RegisterLogger(logger)
+
+	route := must(m.RegisterRoute("/work", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+
+		panic("late failure")
+	}))
+	route.Timeout(10 * time.Millisecond)
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/work", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Logf("[FAIL] :: Expected status code %d but got status code %d.", http.StatusServiceUnavailable, w.Code)
+		t.Fail()
+	}
+
+	select {
+	case line := <-logger:
+		if !strings.Contains(line, "late failure") {
+			t.Logf("[FAIL] :: Expected the panic in the log line but got \"%s\".", line)
+			t.Fail()
+		}
+	case <-time.After(time.Second):
+		t.Log("[FAIL] :: Expected the late panic to be logged.")
+		t.Fail()
+	}
+}
+
+func TestTimeoutRouteMiddleware(t *testing.T) {
+	t.Log("Testing route middleware runs outside the timed out handler, run with -race.")
+
+	logger := NewTestLogger()
+	m := NewMux()
+	m.RegisterLogger(logger)
+	m.AccessLog(AccessLogJSON)
+
+	route := must(m.RegisterRoute("/work", func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		time.Sleep(10 * time.Millisecond)
+	}))
+	route.Use(RequestID(RequestIDConfig{Generate: func() string { return "abc-123" }})).Timeout(time.Millisecond)
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest("GET", "/work", nil))
+
+	if w.Code != http.StatusServiceUnavailable || w.Header().Get("X-Request-ID") != "abc-123" {
+		t.Logf("[FAIL] :: Expected a 503 with the request ID but got %d and %v.", w.Code, w.Header())
+		t.Fail()
+	}
+
+	entries := logger.EntriesAt(LevelWarn)
+	if len(entries) != 1 || entries[0].Fields["request_id"] != "abc-123" {
+		t.Logf("[FAIL] :: Expected the timeout to be logged with the request ID but got %v.", entries)
+		t.Fail()
+	}
+}
@@ -0,0 +1,277 @@
+package mux
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TraceContext is the W3C Trace Context of a request, it identifies the
+// trace and the span that caused the request.
+type TraceContext struct {
+	// TraceID is the 32 hex character ID of the whole trace
+	TraceID string
+	// SpanID is the 16 hex character ID of the span
+	SpanID string
+	// Flags are the trace flags, bit 0 is the sampled flag
+	Flags byte
+	// State is the vendor specific tracestate header
+	State string
+}
+
+// Tracer is implemented by tracing backends. The mux starts a span for
+// every request and names it after the route template once a route has
+// been selected.
+type Tracer interface {
+	// StartSpan starts the span for the request, parent is the context the
+	// request was sent with and is invalid if the request had no context.
+	StartSpan(r *http.Request, parent TraceContext) Span
+}
+
+// Span is a single operation started by a Tracer
+type Span interface {
+	// Context returns the trace context of the span, it is propagated to
+	// the handler through GetTraceContext.
+	Context() TraceContext
+	// SetName renames the span
+	SetName(name string)
+	// SetAttribute adds an attribute to the span
+	SetAttribute(key string, value interface{})
+	// End finishes the span
+	End()
+}
+
+// Tracer sets the tracer used to create a span for every request
+func (m *Mux) Tracer(t Tracer) {
+	m.tracer = t
+}
+
+// GetTraceContext returns the trace context of the request, this is the
+// context of the request's span if the mux has a tracer and otherwise the
+// context the request was sent with. The boolean is false if the request
+// has no valid context.
+func GetTraceContext(r *http.Request) (TraceContext, bool) {
+	tc, ok := r.Context().Value(traceKey).(TraceContext)
+	return tc, ok && tc.Valid()
+}
+
+// ParseTraceContext reads the traceparent and tracestate headers
+func ParseTraceContext(h http.Header) (TraceContext, error) {
+	parts := strings.Split(strings.TrimSpace(h.Get("traceparent")), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return TraceContext{}, errors.New("The traceparent header is malformed")
+	}
+
+	if !isLowerHex(parts[0]) || !isLowerHex(parts[3]) || len(parts[3]) != 2 {
+		return TraceContext{}, errors.New("The traceparent header is malformed")
+	}
+
+	tc := TraceContext{
+		TraceID: parts[1],
+		SpanID:  parts[2],
+		State:   strings.Join(h.Values("tracestate"), ","),
+	}
+
+	flags, _ := hex.DecodeString(parts[3])
+	tc.Flags = flags[0]
+
+	if !tc.Valid() {
+		return TraceContext{}, errors.New("The traceparent header has an invalid trace or span ID")
+	}
+
+	return tc, nil
+}
+
+// Valid reports if the trace and span IDs are well formed and not zero
+func (tc TraceContext) Valid() bool {
+	return len(tc.TraceID) == 32 && isLowerHex(tc.TraceID) && strings.Trim(tc.TraceID, "0") != "" &&
+		len(tc.SpanID) == 16 && isLowerHex(tc.SpanID) && strings.Trim(tc.SpanID, "0") != ""
+}
+
+// Sampled reports if the sampled flag is set
+func (tc TraceContext) Sampled() bool {
+	return tc.Flags&1 == 1
+}
+
+// TraceParent returns the value of the traceparent header
+func (tc TraceContext) TraceParent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", tc.TraceID, tc.SpanID, tc.Flags)
+}
+
+// Inject sets the traceparent and tracestate headers, it is used to
+// propagate the context to outgoing requests.
+func (tc TraceContext) Inject(h http.Header) {
+	if !tc.Valid() {
+		return
+	}
+
+	h.Set("traceparent", tc.TraceParent())
+
+	if tc.State != "" {
+		h.Set("tracestate", tc.State)
+	} else {
+		h.Del("tracestate")
+	}
+}
+
+// startSpan starts the span for the request if the mux has a tracer and
+// returns the request carrying the trace context.
+func (m *Mux) startSpan(r *http.Request) (*http.Request, Span) {
+	parent, _ := ParseTraceContext(r.Header)
+	tc := parent
+
+	var span Span
+	if m.tracer != nil {
+		span = m.tracer.StartSpan(r, parent)
+		span.SetAttribute("http.request.method", r.Method)
+		span.SetAttribute("url.path", r.URL.Path)
+		tc = span.Context()
+	}
+
+	if !tc.Valid() {
+		return r, span
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), traceKey, tc)), span
+}
+
+// endSpan names the span after the route that served the request and
+// ends it.
+func endSpan(span Span, r *http.Request, route *Route, status int) {
+	if span == nil {
+		return
+	}
+
+	name := r.Method
+	if route != nil {
+		name += " " + route.template()
+		span.SetAttribute("http.route", route.template())
+	}
+
+	span.SetName(name)
+	span.SetAttribute("http.response.status_code", status)
+	span.End()
+}
+
+// isLowerHex reports if the string only contains lower case hex digits
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !('0' <= s[i] && s[i] <= '9') && !('a' <= s[i] && s[i] <= 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// randomHex returns n random bytes encoded as hex
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// RecordedSpan is a span stored by SpanRecorder
+type RecordedSpan struct {
+	Name       string
+	Context    TraceContext
+	Parent     TraceContext
+	Attributes map[string]interface{}
+	Start, End time.Time
+	Ended      bool
+}
+
+// SpanRecorder is an in-memory Tracer for tests, it keeps every span it
+// starts and is safe for concurrent use.
+type SpanRecorder struct {
+	mu    sync.Mutex
+	spans []*recorderSpan
+}
+
+// NewSpanRecorder returns an empty SpanRecorder
+func NewSpanRecorder() *SpanRecorder {
+	return &SpanRecorder{}
+}
+
+// StartSpan starts a span that continues the parent's trace, or starts
+// a new sampled trace if the parent is invalid.
+func (s *SpanRecorder) StartSpan(r *http.Request, parent TraceContext) Span {
+	tc := TraceContext{TraceID: randomHex(16), SpanID: randomHex(8), Flags: 1}
+	if parent.Valid() {
+		tc.TraceID, tc.Flags, tc.State = parent.TraceID, parent.Flags, parent.State
+	}
+
+	span := &recorderSpan{recorder: s, span: RecordedSpan{
+		Name:       r.Method,
+		Context:    tc,
+		Parent:     parent,
+		Attributes: make(map[string]interface{}),
+		Start:      time.Now(),
+	}}
+
+	s.mu.Lock()
+	s.spans = append(s.spans, span)
+	s.mu.Unlock()
+
+	return span
+}
+
+// Spans returns a copy of every span started so far
+func (s *SpanRecorder) Spans() []RecordedSpan {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	spans := make([]RecordedSpan, len(s.spans))
+	for i, span := range s.spans {
+		spans[i] = span.span
+		spans[i].Attributes = make(map[string]interface{}, len(span.span.Attributes))
+		for k, v := range span.span.Attributes {
+			spans[i].Attributes[k] = v
+		}
+	}
+
+	return spans
+}
+
+// recorderSpan is the Span returned by SpanRecorder
+type recorderSpan struct {
+	recorder *SpanRecorder
+	span     RecordedSpan
+}
+
+// Context returns the trace context of the span
+func (s *recorderSpan) Context() TraceContext {
+	return s.span.Context
+}
+
+// SetName records the name of the span
+func (s *recorderSpan) SetName(name string) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	s.span.Name = name
+}
+
+// SetAttribute records an attribute on the span
+func (s *recorderSpan) SetAttribute(key string, value interface{}) {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	s.span.Attributes[key] = value
+}
+
+// End records the time the span ended
+func (s *recorderSpan) End() {
+	s.recorder.mu.Lock()
+	defer s.recorder.mu.Unlock()
+
+	s.span.End = time.Now()
+	s.span.Ended = true
+}
@@ -0,0 +1,191 @@
+package mux
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var parseTraceContextTests = []struct {
+	description, traceparent, tracestate string
+	expectError                          bool
+	expected                             TraceContext
+}{{
+	description: "Testing: A valid traceparent is parsed.",
+	traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	tracestate:  "congo=t61rcWkgMzE",
+	expected: TraceContext{
+		TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:  "00f067aa0ba902b7",
+		Flags:   1,
+		State:   "congo=t61rcWkgMzE",
+	},
+}, {
+	description: "Testing: A future version with extra fields is accepted.",
+	traceparent: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra",
+	expected: TraceContext{
+		TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:  "00f067aa0ba902b7",
+	},
+}, {
+	description: "Testing: A missing traceparent is an error.",
+	expectError: true,
+}, {
+	description: "Testing: An all zero trace ID is an error.",
+	traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+	expectError: true,
+}, {
+	description: "Testing: Upper case hex is an error.",
+	traceparent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+	expectError: true,
+}, {
+	description: "Testing: Version 00 with extra fields is an error.",
+	traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	expectError: true,
+}, {
+	description: "Testing: Version ff is an error.",
+	traceparent: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	expectError: true,
+}}
+
+func TestParseTraceContext(t *testing.T) {
+	t.Log("Testing parsing the traceparent and tracestate headers.")
+
+	for i, test := range parseTraceContextTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		h := http.Header{}
+		if test.traceparent != "" {
+			h.Set("traceparent", test.traceparent)
+		}
+		if test.tracestate != "" {
+			h.Set("tracestate", test.tracestate)
+		}
+
+		tc, err := ParseTraceContext(h)
+
+		if test.expectError {
+			if err == nil {
+				t.Logf("[FAIL] :: Expected an error but got %+v.", tc)
+				t.Fail()
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Logf("[FAIL] :: Expected no error but got \"%s\".", err)
+			t.Fail()
+		} else if tc != test.expected {
+			t.Logf("[FAIL] :: Expected %+v but got %+v.", test.expected, tc)
+			t.Fail()
+		}
+	}
+}
+
+var traceTests = []struct {
+	description, requestURL, traceparent, expectedName, expectedRoute string
+	expectedStatus                                                    int
+}{{
+	description:    "Testing: The span is named after the route template.",
+	requestURL:     "/users/42",
+	expectedName:   "GET /users/{id}",
+	expectedRoute:  "/users/{id}",
+	expectedStatus: http.StatusOK,
+}, {
+	description:    "Testing: The span continues the incoming trace.",
+	requestURL:     "/users/42",
+	traceparent:    "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	expectedName:   "GET /users/{id}",
+	expectedRoute:  "/users/{id}",
+	expectedStatus: http.StatusOK,
+}, {
+	description:    "Testing: Unmatched requests are named after the method.",
+	requestURL:     "/missing",
+	expectedName:   "GET",
+	expectedStatus: http.StatusNotFound,
+}}
+
+func TestTracing(t *testing.T) {
+	t.Log("Testing a span is recorded for every request.")
+
+	for i, test := range traceTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		recorder := NewSpanRecorder()
+		m := NewMux()
+		m.Tracer(recorder)
+
+		var handlerContext TraceContext
+		m.RegisterRoute("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+			handlerContext, _ = GetTraceContext(r)
+		})
+
+		r := httptest.NewRequest("GET", test.requestURL, nil)
+		if test.traceparent != "" {
+			r.Header.Set("traceparent", test.traceparent)
+		}
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		spans := recorder.Spans()
+		if len(spans) != 1 {
+			t.Logf("[FAIL] :: Expected one span but got %d.", len(spans))
+			t.Fail()
+			continue
+		}
+
+		span := spans[0]
+		if span.Name != test.expectedName || !span.Ended {
+			t.Logf("[FAIL] :: Expected an ended span named \"%s\" but got \"%s\" (ended: %v).", test.expectedName, span.Name, span.Ended)
+			t.Fail()
+		}
+
+		if route, _ := span.Attributes["http.route"].(string); route != test.expectedRoute {
+			t.Logf("[FAIL] :: Expected the route attribute \"%s\" but got \"%s\".", test.expectedRoute, route)
+			t.Fail()
+		}
+
+		if status := span.Attributes["http.response.status_code"]; status != test.expectedStatus {
+			t.Logf("[FAIL] :: Expected the status attribute %d but got %v.", test.expectedStatus, status)
+			t.Fail()
+		}
+
+		if test.traceparent != "" {
+			parent, _ := ParseTraceContext(r.Header)
+			if span.Context.TraceID != parent.TraceID || span.Parent != parent || span.Context.SpanID == parent.SpanID {
+				t.Logf("[FAIL] :: Expected a child span of %+v but got %+v.", parent, span.Context)
+				t.Fail()
+			}
+		}
+
+		if test.expectedRoute != "" && handlerContext != span.Context {
+			t.Logf("[FAIL] :: Expected the handler to see %+v but it saw %+v.", span.Context, handlerContext)
+			t.Fail()
+		}
+	}
+}
+
+func TestTracePropagationWithoutTracer(t *testing.T) {
+	t.Log("Testing the incoming trace context is propagated without a tracer.")
+
+	m := NewMux()
+
+	outgoing := http.Header{}
+	m.RegisterRoute("/proxy", func(w http.ResponseWriter, r *http.Request) {
+		if tc, ok := GetTraceContext(r); ok {
+			tc.Inject(outgoing)
+		}
+	})
+
+	r := httptest.NewRequest("GET", "/proxy", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.Header.Set("tracestate", "congo=t61rcWkgMzE")
+
+	m.ServeHTTP(httptest.NewRecorder(), r)
+
+	if outgoing.Get("traceparent") != r.Header.Get("traceparent") || outgoing.Get("tracestate") != r.Header.Get("tracestate") {
+		t.Logf("[FAIL] :: Expected the context to be injected but got %v.", outgoing)
+		t.Fail()
+	}
+}
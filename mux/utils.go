@@ -2,23 +2,48 @@ package mux
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 )
 
+// matchResult is the outcome of matching a request to the routes. If no
+// route was selected the status explains why, along with the methods that
+// are allowed for a 405 or the path to redirect to.
+type matchResult struct {
+	route         *Route
+	status        int
+	allowed       []string
+	redirect      string
+	redirectRoute *Route
+	notAcceptable bool
+}
+
 // variableInfo contains the information about the variable
-// that is extracted from the route
+// that is extracted from the route, query is set to the name of
+// the query parameter for variables declared through Route.Queries.
+// Optional variables may be missing from the request, in which case
+// the default value is used if the variable declares one. Greedy
+// variables take the longest value when they share a segment.
 type variableInfo struct {
-	name, route, kind string
+	name, route, kind, query string
+
+	optional, hasDefault, greedy bool
+	defaultValue                 string
 }
 
 // containsRoute performs a simple check on if the route is
 // already registered in the multiplexer. This is matched
 // exactly so the same route can be registered if the variable
 // names are different
-func (m *Mux) containsRoute(route string) (int, bool) {
+//
+// Unless the mux uses the lenient trailing slash policy, routes
+// with and without a trailing "/" are different routes.
+func (m *Mux) containsRoute(route string, trailingSlash bool) (int, bool) {
+	lenient := m.slashPolicy == 0 || m.slashPolicy == TrailingSlashLenient
+
 	for i, r := range m.routes {
-		if r.url == route {
+		if r.url == route && !r.hasConditions() && (lenient || r.trailingSlash == trailingSlash) {
 			return i, true
 		}
 	}
@@ -32,26 +57,56 @@ func (m *Mux) containsRoute(route string) (int, bool) {
 // Exact matching is used of there are no variables in the route.
 // If there are variables in the route then it matches around those
 func matchRoute(route Route, requestURL string) bool {
-	if requestURL[len(requestURL)-1] == '/' {
+	return matchPathCase(route, requestURL, false, false)
+}
+
+// matchPathCase matches the request path to the route, the path is split
+// as an escaped path if encoded is set and literals are compared without
+// case if fold is set.
+func matchPathCase(route Route, requestURL string, encoded, fold bool) bool {
+	if encoded {
+		return matchSegments(route, splitPath(requestURL, true), fold)
+	}
+
+	if len(requestURL) > 0 && requestURL[len(requestURL)-1] == '/' {
 		requestURL = requestURL[:len(requestURL)-1]
 	}
 
 	if !route.hasVariables {
-		return route.url == requestURL
+		return route.url == requestURL || (fold && strings.EqualFold(route.url, requestURL))
 	}
+
+	return matchSegments(route, cleanSlice(strings.Split(requestURL, "/")), fold)
+}
+
+// matchSegments matches the segments of the request path to the
+// segments of the route. Optional segments at the end of the route
+// may be missing from the request.
+func matchSegments(route Route, reqBlocks []string, fold bool) bool {
 	urlBlocks := cleanSlice(strings.Split(route.url, "/"))
-	reqBlocks := cleanSlice(strings.Split(requestURL, "/"))
 
-	if len(urlBlocks) != len(reqBlocks) {
+	required := len(urlBlocks)
+	for required > 0 && isOptionalVariable(urlBlocks[required-1]) {
+		required--
+	}
+
+	if len(reqBlocks) < required || len(reqBlocks) > len(urlBlocks) {
 		return false
 	}
 
-	for i, block := range urlBlocks {
-		if block[0] == '{' && block[len(block)-1] == '}' {
+	for i, block := range urlBlocks[:len(reqBlocks)] {
+		if isVariable(block) {
 			continue
 		}
 
-		if block != reqBlocks[i] {
+		if isMixedSegment(block) {
+			if _, ok := matchMixedSegment(block, reqBlocks[i], fold); !ok {
+				return false
+			}
+			continue
+		}
+
+		if block != reqBlocks[i] && !(fold && strings.EqualFold(block, reqBlocks[i])) {
 			return false
 		}
 	}
@@ -74,6 +129,18 @@ func getVariablesFromRoute(route string) ([]variableInfo, error) {
 		return nil, err
 	}
 
+	err = checkOptionalSegments(route)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkMixedSegments(route)
+
+	if err != nil {
+		return nil, err
+	}
+
 	infoSplice := []variableInfo{}
 	for _, variable := range variables {
 		info, err := getVariableInfo(variable)
@@ -89,24 +156,190 @@ func getVariablesFromRoute(route string) ([]variableInfo, error) {
 	return infoSplice, nil
 }
 
+// match finds the route that should serve the request. When no route is
+// selected the result says why: a redirect if a route only differs by the
+// trailing "/" or the case of its literals, 405 along with the allowed
+// methods if a route matched everything but the method, 406 if a route
+// matched everything but the media types it produces and 404 otherwise.
+// Subtree routes are only selected if no route matches the request exactly,
+// the one with the longest path wins.
+func (m *Mux) match(r *http.Request) (result matchResult) {
+	var redirect, subtree *Route
+
+	requestPath := m.requestPath(r)
+
+	for _, route := range m.routes {
+		pm := m.matchPath(route, requestPath)
+		if pm == noMatch {
+			continue
+		}
+
+		if !route.matchConditions(r) {
+			m.logRequest(r, LevelDebug, "Route matched the path but not its conditions", "route", route.template(), "method", r.Method, "path", requestPath)
+			continue
+		}
+
+		if !route.allowsMethod(r.Method) {
+			m.logRequest(r, LevelDebug, "Route matched the path but not its methods", "route", route.template(), "method", r.Method, "path", requestPath)
+
+			// a subtree at the root matches every path, it would turn every
+			// 404 into a 405 so it only counts for the root itself
+			if !route.subtree || route.depth() > 0 || len(splitPath(requestPath, m.encodedPath)) == 0 {
+				result.allowed = appendUnique(result.allowed, route.allowMethods()...)
+			}
+			continue
+		}
+
+		if len(route.produces) > 0 && !acceptsAny(r.Header.Get("Accept"), route.produces) {
+			m.logRequest(r, LevelDebug, "Route matched the path but doesn't produce an accepted media type", "route", route.template(), "accept", r.Header.Get("Accept"), "path", requestPath)
+			result.notAcceptable = true
+			continue
+		}
+
+		if pm == redirectMatch {
+			if redirect == nil {
+				redirect = route
+			}
+			continue
+		}
+
+		if route.subtree {
+			if subtree == nil || route.depth() > subtree.depth() {
+				subtree = route
+			}
+			continue
+		}
+
+		m.logRequest(r, LevelDebug, "Route matched", "route", route.template(), "method", r.Method, "path", requestPath)
+		result.route = route
+		result.status = http.StatusOK
+		return
+	}
+
+	if subtree != nil {
+		m.logRequest(r, LevelDebug, "Route matched", "route", subtree.template(), "method", r.Method, "path", requestPath)
+		result.route = subtree
+		result.status = http.StatusOK
+		return
+	}
+
+	if redirect != nil {
+		result.redirect = m.canonicalPath(redirect, requestPath)
+		result.redirectRoute = redirect
+		m.logRequest(r, LevelDebug, "Redirecting to the canonical path", "route", redirect.template(), "method", r.Method, "path", requestPath, "location", result.redirect)
+		return
+	}
+
+	if len(result.allowed) > 0 {
+		result.status = http.StatusMethodNotAllowed
+	} else if result.notAcceptable {
+		result.status = http.StatusNotAcceptable
+	} else {
+		result.status = http.StatusNotFound
+	}
+
+	m.logRequest(r, LevelDebug, "No route matched", "method", r.Method, "path", requestPath, "status", result.status)
+	return
+}
+
+// requestVariables returns the variables of the route serving the request,
+// if the request didn't come through ServeHTTP the variables of every route
+// matching the path are returned.
+func (m *Mux) requestVariables(r *http.Request) []variableInfo {
+	if route := routeFromRequest(r); route != nil {
+		return route.variables
+	}
+
+	var infoList []variableInfo
+	requestPath := m.requestPath(r)
+	for _, route := range m.routes {
+		if m.matchPath(route, requestPath) != noMatch {
+			infoList = append(infoList, route.variables...)
+		}
+	}
+
+	return infoList
+}
+
 // getVariableFromRequest returns the value from the request
-func getVariableFromRequest(info variableInfo, request string) (val interface{}, err error) {
+func (m *Mux) getVariableFromRequest(info variableInfo, r *http.Request) (val interface{}, err error) {
+	if info.query != "" {
+		values, ok := r.URL.Query()[info.query]
+		if !ok || len(values) == 0 {
+			val, err = getDefaultValue(info)
+			return
+		}
+
+		val, err = cast(info.kind, values[0])
+		return
+	}
+
 	urlBlocks := cleanSlice(strings.Split(info.route, "/"))
-	reqBlocks := cleanSlice(strings.Split(request, "/"))
+	reqBlocks := m.requestSegments(r)
 
 	for i, block := range urlBlocks {
-		if strings.Contains(block, info.name) {
-			val, err = cast(info.kind, reqBlocks[i])
+		index := variableIndex(block, info.name)
+		if index < 0 {
+			continue
+		}
+
+		if i >= len(reqBlocks) {
+			val, err = getDefaultValue(info)
+			return
+		}
 
-			if err != nil {
-				val = nil
+		value := reqBlocks[i]
+		if isMixedSegment(block) {
+			values, ok := matchMixedSegment(block, value, true)
+			if !ok {
+				return
 			}
+
+			value = values[index]
+		}
+
+		val, err = cast(info.kind, value)
+
+		if err != nil {
+			val = nil
 		}
 	}
 
 	return
 }
 
+// variableIndex returns the position of the named variable among the
+// variables of the block, or -1 if the block doesn't declare it.
+func variableIndex(block, name string) int {
+	if !strings.Contains(block, "{") {
+		return -1
+	}
+
+	variables, err := getVariableStrings(block)
+	if err != nil {
+		return -1
+	}
+
+	for i, variable := range variables {
+		info, err := getVariableInfo(variable)
+		if err == nil && info.name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// getDefaultValue returns the default value of a variable that is missing
+// from the request, nil is returned if the variable has no default.
+func getDefaultValue(info variableInfo) (interface{}, error) {
+	if !info.hasDefault {
+		return nil, nil
+	}
+
+	return cast(info.kind, info.defaultValue)
+}
+
 // getVariableStrings - Returns all the strings for the variables found
 // inside of a route. For example:
 //
@@ -133,6 +366,11 @@ func getVariableStrings(route string) ([]string, error) {
 // getVariableInfo extracts the information from the block of the
 // route that contains the variable decleraton and will return
 // an error if any information is missing.
+//
+// A variable is optional if its declaration ends in "?", such as
+// "{month: int?}", or if it declares a default value, such as
+// "{page: int=1}". A declaration ending in "+", such as "{name+}",
+// makes the variable greedy.
 func getVariableInfo(variable string) (variableInfo, error) {
 	decon := variable[1 : len(variable)-1]
 
@@ -144,6 +382,30 @@ func getVariableInfo(variable string) (variableInfo, error) {
 		return variableInfo{}, errors.New("Missing variable information in variable declaration")
 	}
 
+	var optional, hasDefault, greedy bool
+	var defaultValue string
+
+	if i := strings.Index(decon, "="); i >= 0 {
+		optional, hasDefault = true, true
+		defaultValue = strings.TrimSpace(decon[i+1:])
+		decon = decon[:i]
+	}
+
+	decon = strings.TrimSpace(decon)
+	if strings.HasSuffix(decon, "?") {
+		optional = true
+		decon = decon[:len(decon)-1]
+	}
+
+	if strings.HasSuffix(decon, "+") {
+		greedy = true
+		decon = decon[:len(decon)-1]
+	}
+
+	if strings.TrimSpace(decon) == "" || strings.Index(decon, ":") == 0 {
+		return variableInfo{}, errors.New("Missing the variable name in variable declaration")
+	}
+
 	pieces := strings.Split(decon, ":")
 	// kindString needs to default to "string" since we are just using a string value to store
 	// the kind and we want "string" as the default case
@@ -152,11 +414,75 @@ func getVariableInfo(variable string) (variableInfo, error) {
 		kindString = strings.TrimSpace(pieces[1])
 	}
 
-	info := variableInfo{name: strings.TrimSpace(pieces[0]), kind: strings.ToLower(kindString)}
+	info := variableInfo{
+		name:         strings.TrimSpace(pieces[0]),
+		kind:         strings.ToLower(kindString),
+		optional:     optional,
+		hasDefault:   hasDefault,
+		greedy:       greedy,
+		defaultValue: defaultValue,
+	}
+
+	if hasDefault {
+		if _, err := cast(info.kind, defaultValue); err != nil {
+			return variableInfo{}, fmt.Errorf("Default value \"%s\" can't be converted to %s", defaultValue, info.kind)
+		}
+	}
 
 	return info, nil
 }
 
+// checkOptionalSegments - Checks that optional variables are only
+// followed by other optional variables since only the end of the
+// route can be left out of a request
+func checkOptionalSegments(route string) error {
+	optional := false
+
+	for _, block := range cleanSlice(strings.Split(route, "/")) {
+		if isOptionalVariable(block) {
+			optional = true
+			continue
+		}
+
+		if optional {
+			return errors.New("Optional variables can only be followed by other optional variables")
+		}
+	}
+
+	return nil
+}
+
+// checkMixedSegments - Checks that the blocks of the route that mix
+// literals and variables can be parsed
+func checkMixedSegments(route string) error {
+	for _, block := range cleanSlice(strings.Split(route, "/")) {
+		if !isMixedSegment(block) {
+			continue
+		}
+
+		if _, err := segmentPattern(block, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isOptionalVariable reports if the block of the route is a variable
+// that may be left out of the request
+func isOptionalVariable(block string) bool {
+	if !isVariable(block) {
+		return false
+	}
+
+	decon := block[1 : len(block)-1]
+	if strings.Contains(decon, "=") {
+		return true
+	}
+
+	return strings.HasSuffix(strings.TrimSpace(decon), "?")
+}
+
 // checkVariablSyntax - Checks if the number of braces matches up
 // and throws an error if there's a missing brace
 func checkVariableSyntax(route string) error {
@@ -198,26 +524,91 @@ func cleanSlice(slice []string) []string {
 	return newSlice
 }
 
-// call sends the response writer and request to whichever handler
-// type has been initialized
-func call(route Route, w http.ResponseWriter, r *http.Request) {
-	if route.handler.handler == nil {
-		route.handler.handlerFunc(w, r)
+// appendUnique appends the values to the slice skipping any values that
+// are already in the slice
+func appendUnique(slice []string, values ...string) []string {
+	for _, v := range values {
+		found := false
+		for _, s := range slice {
+			if s == v {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			slice = append(slice, v)
+		}
+	}
+
+	return slice
+}
+
+// serveError calls the error handler for the status code in the scope of
+// the request, if no handler applies the status text, or a problem if
+// problem details are enabled, is returned with the status code.
+func (m *Mux) serveError(w http.ResponseWriter, r *http.Request, status int) {
+	if h := m.errorHandler(r, status); h != nil {
+		h(w, r)
 		return
 	}
 
-	route.handler.handler.ServeHTTP(w, r)
+	defaultError(w, r, status)
+}
+
+// errorHandler resolves the error handler for the status, the groups of the
+// request are searched from the innermost before the mux, then the muxes it
+// is mounted in are searched the same way before the defaults of the mux.
+func (m *Mux) errorHandler(r *http.Request, status int) http.HandlerFunc {
+	state := stateFromRequest(r)
+	if state == nil || state.mux != m {
+		state = &requestState{mux: m}
+	}
+
+	for ; state != nil; state = state.parent {
+		if h := state.mux.registeredErrorHandler(r, state.route, status); h != nil {
+			return h
+		}
+	}
+
+	return m.errorHandlers[status]
+}
+
+// registeredErrorHandler returns the error handler registered for the
+// status on the groups of the route or on the mux, defaults are skipped.
+func (m *Mux) registeredErrorHandler(r *http.Request, route *Route, status int) http.HandlerFunc {
+	for g := m.requestGroup(r, route); g != nil; g = g.parent {
+		if h := scopedErrorHandler(g.errorHandlers, nil, status); h != nil {
+			return h
+		}
+	}
+
+	return scopedErrorHandler(m.errorHandlers, m.defaultErrorHandlers, status)
+}
+
+// scopedErrorHandler returns the handler for the status, its class or the
+// catch-all in that order. Default handlers are skipped.
+func scopedErrorHandler(handlers map[int]http.HandlerFunc, defaults map[int]bool, status int) http.HandlerFunc {
+	for _, key := range []int{status, errorClass(status), AllErrors} {
+		if h, ok := handlers[key]; ok && !defaults[key] {
+			return h
+		}
+	}
+
+	return nil
 }
 
 // register does the actual registration of handlers to the multiplexer,
 // this lets us have the same functionality between both of the
 // registration methods while still providing two methods of registration.
 func (m *Mux) register(route string, gh gowtHandler) (*Route, error) {
-	if route[len(route)-1] == '/' {
+	trailingSlash := hasTrailingSlash(route)
+
+	if len(route) > 0 && route[len(route)-1] == '/' {
 		route = route[:len(route)-1]
 	}
 
-	i, ok := m.containsRoute(route)
+	i, ok := m.containsRoute(route, trailingSlash)
 
 	variables, err := getVariablesFromRoute(route)
 
@@ -226,19 +617,22 @@ func (m *Mux) register(route string, gh gowtHandler) (*Route, error) {
 	}
 
 	if ok {
+		m.log(LevelWarn, "Route was already registered, its handler has been replaced", "route", m.routes[i].template())
 		m.routes[i].handler = gh
 		m.routes[i].variables = variables
 		m.routes[i].hasVariables = len(variables) > 0
-		return &m.routes[i], nil
+		m.routes[i].trailingSlash = trailingSlash
+		return m.routes[i], nil
 	}
 
-	r := Route{
-		url:          route,
-		handler:      gh,
-		variables:    variables,
-		hasVariables: len(variables) > 0,
+	r := &Route{
+		url:           route,
+		handler:       gh,
+		variables:     variables,
+		hasVariables:  len(variables) > 0,
+		trailingSlash: trailingSlash,
 	}
 	m.routes = append(m.routes, r)
 
-	return &r, nil
+	return r, nil
 }
@@ -41,6 +41,37 @@ var routeExtractionTests = []struct {
 	route:        "/test/{}/test",
 	expected:     nil,
 	errorMessage: "Missing variable information in variable declaration",
+}, {
+	description: "Testing: When providing optional variables, the variables are extracted without the optional marker.",
+	route:       "/reports/{year: int}/{month: int?}",
+	expected:    []variableInfo{variableInfo{name: "year", kind: "int"}, variableInfo{name: "month", kind: "int"}},
+}, {
+	description: "Testing: When providing a variable with a default value, the variable is extracted without the default.",
+	route:       "/list/{page: int=1}",
+	expected:    []variableInfo{variableInfo{name: "page", kind: "int"}},
+}, {
+	description: "Testing: When providing variables that share a segment, all of the variables are extracted.",
+	route:       "/files/{name}.{ext: string}",
+	expected:    []variableInfo{variableInfo{name: "name", kind: "string"}, variableInfo{name: "ext", kind: "string"}},
+}, {
+	description: "Testing: When providing a greedy variable, the variable is extracted without the greedy marker.",
+	route:       "/v{version: int+}/items",
+	expected:    []variableInfo{variableInfo{name: "version", kind: "int"}},
+}, {
+	description:  "Testing: When providing an optional variable that shares a segment, the variable will not be extracted.",
+	route:        "/files/{name}.{ext?}",
+	expected:     nil,
+	errorMessage: "Optional variables can't share a segment with other text",
+}, {
+	description:  "Testing: When providing an optional variable followed by a required segment, the variables will not be extracted.",
+	route:        "/reports/{month?}/summary",
+	expected:     nil,
+	errorMessage: "Optional variables can only be followed by other optional variables",
+}, {
+	description:  "Testing: When providing a default value that can't be converted to the variable type, the variable will not be extracted.",
+	route:        "/list/{page: int=first}",
+	expected:     nil,
+	errorMessage: "Default value \"first\" can't be converted to int",
 }}
 
 func TestRouteExtraction(t *testing.T) {
@@ -127,6 +158,41 @@ var routeMatchingTests = []struct {
 	requestURL:    "/other/testing/tested/",
 	route:         Route{url: "/other/testing/tested", hasVariables: false},
 	expectedMatch: true,
+}, {
+	description:   "Testing: Matching a route with a missing optional variable should match.",
+	requestURL:    "/reports/2024",
+	route:         Route{url: "/reports/{year:int}/{month:int?}", hasVariables: true, variables: []variableInfo{variableInfo{}, variableInfo{}}},
+	expectedMatch: true,
+}, {
+	description:   "Testing: Matching a route with a missing variable that has a default should match.",
+	requestURL:    "/list",
+	route:         Route{url: "/list/{page:int=1}", hasVariables: true, variables: []variableInfo{variableInfo{}}},
+	expectedMatch: true,
+}, {
+	description:   "Testing: Matching a route with variables and literals in one segment should match.",
+	requestURL:    "/files/report.pdf",
+	route:         Route{url: "/files/{name}.{ext}", hasVariables: true, variables: []variableInfo{variableInfo{}, variableInfo{}}},
+	expectedMatch: true,
+}, {
+	description:   "Testing: Non-matching literals in a segment with variables shouldn't match.",
+	requestURL:    "/files/report",
+	route:         Route{url: "/files/{name}.{ext}", hasVariables: true, variables: []variableInfo{variableInfo{}, variableInfo{}}},
+	expectedMatch: false,
+}, {
+	description:   "Testing: A typed variable that shares a segment shouldn't match a value of another type.",
+	requestURL:    "/vtwo/items",
+	route:         Route{url: "/v{version: int}/items", hasVariables: true, variables: []variableInfo{variableInfo{}}},
+	expectedMatch: false,
+}, {
+	description:   "Testing: Matching a route with more segments than the route has shouldn't match.",
+	requestURL:    "/reports/2024/10/01",
+	route:         Route{url: "/reports/{year:int}/{month:int?}", hasVariables: true, variables: []variableInfo{variableInfo{}, variableInfo{}}},
+	expectedMatch: false,
+}, {
+	description:   "Testing: Matching an empty request path shouldn't panic and should only match the root route.",
+	requestURL:    "",
+	route:         Route{url: "", hasVariables: false},
+	expectedMatch: true,
 }}
 
 func TestRouteMatching(t *testing.T) {
@@ -0,0 +1,280 @@
+package mux
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Versioning configures how the mux resolves the API version of a request
+// for routes that have handlers registered per version. The sources are
+// checked in the order of the fields, the first one that provides a version
+// is used.
+type Versioning struct {
+	// PathPrefix resolves the version from the first segment of the path,
+	// "/v2/users" is served by the "/users" route using version "v2". The
+	// segment is only treated as a version if the rest of the path is
+	// served by a route that has the version, otherwise the whole path is
+	// matched.
+	PathPrefix bool
+	// Header is the name of a request header holding the version, such as
+	// "API-Version".
+	Header string
+	// MediaType is a vendor media type, such as "application/vnd.acme", that
+	// resolves "Accept: application/vnd.acme.v2+json" and
+	// "Accept: application/vnd.acme+json; version=2" to version "v2".
+	MediaType string
+	// Default is the version used when the request doesn't ask for one.
+	Default string
+}
+
+// routeVersion is a handler registered for one version of a route
+type routeVersion struct {
+	name    string
+	handler gowtHandler
+	sunset  time.Time
+}
+
+// Versioning sets how the API version of requests is resolved
+func (m *Mux) Versioning(v Versioning) {
+	m.versioning = v
+}
+
+// Version registers a handler that serves the route for the version
+// provided. Requests that don't resolve to a version are served by the
+// handler the route was registered with, requests for a version the route
+// doesn't have get a 404, or a 406 if the version came from the Accept
+// header.
+func (r *Route) Version(version string, handler http.Handler) *Route {
+	return r.addVersion(version, gowtHandler{handler: handler})
+}
+
+// VersionFunc registers a HandlerFunc that serves the route for the
+// version provided, see Version.
+func (r *Route) VersionFunc(version string, handler http.HandlerFunc) *Route {
+	return r.addVersion(version, gowtHandler{handlerFunc: handler})
+}
+
+// Deprecate marks a version of the route as deprecated, responses for that
+// version carry a Sunset header with the date the version will be removed.
+func (r *Route) Deprecate(version string, sunset time.Time) *Route {
+	if v, ok := r.versions[normalizeVersion(version)]; ok {
+		v.sunset = sunset
+		r.versions[normalizeVersion(version)] = v
+	}
+
+	return r
+}
+
+// APIVersion returns the API version that was resolved for the request, an
+// empty string is returned if the request didn't resolve to a version.
+func APIVersion(r *http.Request) string {
+	version, _ := r.Context().Value(versionKey).(string)
+	return version
+}
+
+// addVersion stores the handler for the version of the route
+func (r *Route) addVersion(version string, gh gowtHandler) *Route {
+	if r.versions == nil {
+		r.versions = make(map[string]routeVersion)
+	}
+
+	key := normalizeVersion(version)
+	v := r.versions[key]
+	v.name = version
+	v.handler = gh
+	r.versions[key] = v
+
+	return r
+}
+
+// hasVersion reports if any route has a handler for the version
+func (m *Mux) hasVersion(version string) bool {
+	key := normalizeVersion(version)
+
+	for _, route := range m.routes {
+		if _, ok := route.versions[key]; ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// stripVersionPrefix removes a version from the start of the request path
+// when the mux resolves versions from the path and a route has the version.
+// The version is returned along with a shallow copy of the request using the
+// remaining path.
+func (m *Mux) stripVersionPrefix(r *http.Request) (*http.Request, string) {
+	if !m.versioning.PathPrefix {
+		return r, ""
+	}
+
+	p := m.requestPath(r)
+	segments := strings.SplitN(strings.TrimPrefix(p, "/"), "/", 2)
+	if segments[0] == "" || !m.hasVersion(segments[0]) {
+		return r, ""
+	}
+
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = stripFirstSegment(r.URL.Path)
+	if r.URL.RawPath != "" {
+		r2.URL.RawPath = stripFirstSegment(r.URL.RawPath)
+	}
+
+	return r2, segments[0]
+}
+
+// matchVersion matches the request, a version prefix is only stripped from
+// the path if the rest of the path is served by a route with that version.
+// Otherwise the whole path is matched, unless nothing serves it and the rest
+// of the path only failed on the method or media type.
+func (m *Mux) matchVersion(r *http.Request) (*http.Request, string, matchResult) {
+	stripped, version := m.stripVersionPrefix(r)
+	if version == "" {
+		return r, "", m.match(r)
+	}
+
+	versioned := m.match(stripped)
+
+	route := versioned.route
+	if versioned.redirect != "" {
+		route = versioned.redirectRoute
+	}
+
+	if route != nil {
+		if _, ok := route.versions[normalizeVersion(version)]; ok {
+			return stripped, version, versioned
+		}
+	}
+
+	result := m.match(r)
+	if result.route == nil && result.redirect == "" && result.status == http.StatusNotFound &&
+		versioned.route == nil && versioned.redirect == "" && versioned.status != http.StatusNotFound {
+		return stripped, version, versioned
+	}
+
+	return r, "", result
+}
+
+// versionHandler picks the handler of the route for the version the
+// request asks for. The status is 0 if a handler was found and otherwise
+// says why the version couldn't be served.
+func (m *Mux) versionHandler(route *Route, r *http.Request, pathVersion string) (gowtHandler, string, int) {
+	if len(route.versions) == 0 {
+		return route.handler, pathVersion, 0
+	}
+
+	version, status := pathVersion, http.StatusNotFound
+
+	if version == "" && m.versioning.Header != "" {
+		version = r.Header.Get(m.versioning.Header)
+	}
+
+	if version == "" && m.versioning.MediaType != "" {
+		version = mediaTypeVersion(r.Header.Get("Accept"), m.versioning.MediaType)
+		if version != "" {
+			status = http.StatusNotAcceptable
+		}
+	}
+
+	if version == "" {
+		version = m.versioning.Default
+	}
+
+	if version == "" {
+		return route.handler, "", 0
+	}
+
+	v, ok := route.versions[normalizeVersion(version)]
+	if !ok {
+		return gowtHandler{}, version, status
+	}
+
+	return v.handler, v.name, 0
+}
+
+// serveVersion sets the response headers for the version and serves
+// the request with the handler for the version.
+func (m *Mux) serveVersion(route *Route, gh gowtHandler, version string, w http.ResponseWriter, r *http.Request) {
+	if len(route.versions) > 0 {
+		if m.versioning.Header != "" {
+			w.Header().Add("Vary", m.versioning.Header)
+		}
+
+		if m.versioning.MediaType != "" {
+			w.Header().Add("Vary", "Accept")
+		}
+
+		if v, ok := route.versions[normalizeVersion(version)]; ok && !v.sunset.IsZero() {
+			w.Header().Set("Sunset", v.sunset.UTC().Format(http.TimeFormat))
+		}
+	}
+
+	if version != "" {
+		r = r.WithContext(context.WithValue(r.Context(), versionKey, version))
+	}
+
+	groupChain(route.group, chain(route.middleware, m.timeoutHandler(route, gh))).ServeHTTP(w, r)
+}
+
+// mediaTypeVersion finds the version in an Accept header for the vendor
+// media type, either as a suffix such as "application/vnd.acme.v2+json"
+// or as a parameter such as "application/vnd.acme+json; version=2".
+func mediaTypeVersion(accept, vendor string) string {
+	vendor = strings.ToLower(vendor)
+
+	for _, entry := range strings.Split(accept, ",") {
+		params := strings.Split(entry, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+
+		if !strings.HasPrefix(mediaType, vendor) {
+			continue
+		}
+
+		// the vendor has to end at a boundary, "application/vnd.acmecorp"
+		// isn't a version of "application/vnd.acme"
+		rest := mediaType[len(vendor):]
+		if rest != "" && rest[0] != '.' && rest[0] != '+' {
+			continue
+		}
+
+		for _, param := range params[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+			if len(kv) == 2 && strings.EqualFold(kv[0], "version") {
+				return strings.Trim(kv[1], `"`)
+			}
+		}
+
+		if i := strings.Index(rest, "+"); i >= 0 {
+			rest = rest[:i]
+		}
+
+		if rest = strings.TrimPrefix(rest, "."); rest != "" {
+			return rest
+		}
+	}
+
+	return ""
+}
+
+// stripFirstSegment removes the first segment from the path
+func stripFirstSegment(p string) string {
+	segments := strings.SplitN(strings.TrimPrefix(p, "/"), "/", 2)
+	if len(segments) < 2 {
+		return "/"
+	}
+
+	return "/" + segments[1]
+}
+
+// normalizeVersion returns the key a version is stored under so that
+// "v2", "V2" and "2" are the same version.
+func normalizeVersion(version string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(version)), "v")
+}
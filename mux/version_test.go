@@ -0,0 +1,129 @@
+package mux
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var versionRoutingTests = []struct {
+	description, requestURL string
+	headers                 map[string]string
+	versioning              Versioning
+	expectedResponse        response
+	expectedSunset          string
+}{{
+	description:      "Testing: A version prefix in the path is served by the handler for that version.",
+	requestURL:       "/v2/users",
+	versioning:       Versioning{PathPrefix: true},
+	expectedResponse: response{Body: "v2 v2", Code: http.StatusOK},
+}, {
+	description:      "Testing: A version in the configured header is served by the handler for that version.",
+	requestURL:       "/users",
+	headers:          map[string]string{"API-Version": "1"},
+	versioning:       Versioning{Header: "API-Version"},
+	expectedResponse: response{Body: "v1 v1", Code: http.StatusOK},
+	expectedSunset:   "Fri, 01 Jan 2027 00:00:00 GMT",
+}, {
+	description:      "Testing: A version suffix on the vendor media type is served by the handler for that version.",
+	requestURL:       "/users",
+	headers:          map[string]string{"Accept": "text/html, application/vnd.acme.v2+json"},
+	versioning:       Versioning{MediaType: "application/vnd.acme"},
+	expectedResponse: response{Body: "v2 v2", Code: http.StatusOK},
+}, {
+	description:      "Testing: A version parameter on the vendor media type is served by the handler for that version.",
+	requestURL:       "/users",
+	headers:          map[string]string{"Accept": "application/vnd.acme+json; version=2"},
+	versioning:       Versioning{MediaType: "application/vnd.acme"},
+	expectedResponse: response{Body: "v2 v2", Code: http.StatusOK},
+}, {
+	description:      "Testing: A request without a version is served by the default version.",
+	requestURL:       "/users",
+	versioning:       Versioning{Header: "API-Version", Default: "v2"},
+	expectedResponse: response{Body: "v2 v2", Code: http.StatusOK},
+}, {
+	description:      "Testing: A request without a version and without a default is served by the route's handler.",
+	requestURL:       "/users",
+	versioning:       Versioning{Header: "API-Version"},
+	expectedResponse: response{Body: "base", Code: http.StatusOK},
+}, {
+	description:      "Testing: A request for a version the route doesn't have returns 404.",
+	requestURL:       "/users",
+	headers:          map[string]string{"API-Version": "v9"},
+	versioning:       Versioning{Header: "API-Version"},
+	expectedResponse: response{Body: http.StatusText(http.StatusNotFound), Code: http.StatusNotFound},
+}, {
+	description:      "Testing: A media type for a version the route doesn't have returns 406.",
+	requestURL:       "/users",
+	headers:          map[string]string{"Accept": "application/vnd.acme.v9+json"},
+	versioning:       Versioning{MediaType: "application/vnd.acme"},
+	expectedResponse: response{Body: http.StatusText(http.StatusNotAcceptable), Code: http.StatusNotAcceptable},
+}, {
+	description:      "Testing: A media type that only starts with the vendor is served by the default version.",
+	requestURL:       "/users",
+	headers:          map[string]string{"Accept": "application/vnd.acmecorp+json"},
+	versioning:       Versioning{MediaType: "application/vnd.acme", Default: "v1"},
+	expectedResponse: response{Body: "v1 v1", Code: http.StatusOK},
+	expectedSunset:   "Fri, 01 Jan 2027 00:00:00 GMT",
+}, {
+	description:      "Testing: A path starting with a version is matched whole when a route has that path.",
+	requestURL:       "/v2/status",
+	versioning:       Versioning{PathPrefix: true},
+	expectedResponse: response{Body: "status", Code: http.StatusOK},
+}, {
+	description:      "Testing: A version prefix isn't stripped for a route that doesn't have the version.",
+	requestURL:       "/v2/health",
+	versioning:       Versioning{PathPrefix: true},
+	expectedResponse: response{Body: http.StatusText(http.StatusNotFound), Code: http.StatusNotFound},
+}}
+
+func TestVersionRouting(t *testing.T) {
+	t.Log("Testing routing requests to the handler for their API version.")
+
+	versionHandler := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintf(w, "%s %s\n", name, APIVersion(r))
+		}
+	}
+
+	for i, test := range versionRoutingTests {
+		t.Logf("[ %02d ] %s", i+1, test.description)
+
+		m := NewMux()
+		m.Versioning(test.versioning)
+
+		route := must(m.RegisterRoute("/users", writeBody("base")))
+		route.VersionFunc("v1", versionHandler("v1")).
+			VersionFunc("v2", versionHandler("v2")).
+			Deprecate("v1", time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC))
+		m.RegisterRoute("/v2/status", writeBody("status"))
+		m.RegisterRoute("/health", writeBody("health"))
+
+		r := httptest.NewRequest("GET", test.requestURL, nil)
+		for k, v := range test.headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+
+		m.ServeHTTP(w, r)
+
+		if w.Code != test.expectedResponse.Code {
+			t.Logf("[FAIL] :: Expected status code %d but got status code %d.", test.expectedResponse.Code, w.Code)
+			t.Fail()
+		}
+
+		body := strings.TrimSpace(w.Body.String())
+		if body != test.expectedResponse.Body {
+			t.Logf("[FAIL] :: Expected body \"%s\" but got body \"%s\".", test.expectedResponse.Body, body)
+			t.Fail()
+		}
+
+		if sunset := w.Header().Get("Sunset"); sunset != test.expectedSunset {
+			t.Logf("[FAIL] :: Expected Sunset header \"%s\" but got \"%s\".", test.expectedSunset, sunset)
+			t.Fail()
+		}
+	}
+}